@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"os"
-	"time"
 
 	"gioui.org/app"
 	"gioui.org/unit"
@@ -13,19 +15,31 @@ import (
 )
 
 func main() {
-
-	// Fetch available tags
-	go func() {
-		api.FetchCAASTags(30 * time.Second)
-	}()
+	offline := flag.Bool("offline", false, "show only cats cached by earlier runs, without going to the network")
+	flag.Parse()
+	ui.Offline = *offline
+
+	// Keep the available tags loaded and fresh for as long as the window
+	// is open, or use the cached ones when offline
+	ctx, cancel := context.WithCancel(context.Background())
+	if *offline {
+		if err := api.AvailableTags.LoadCache(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error loading tag cache: %v", err)
+		}
+	} else {
+		go api.AvailableTags.AutoRefresh(ctx, api.NewClient())
+	}
 
 	// Make a window and run the loop
 	go func() {
-		// Create window
+		// Create window, tall enough for the tag and says panels to leave
+		// the cat some room
 		w := new(app.Window)
-		w.Option(app.Title("CatFetch"), app.Size(unit.Dp(400), unit.Dp(500)))
+		w.Option(app.Title("CatFetch"), app.Size(unit.Dp(480), unit.Dp(800)))
 
-		if err := ui.Run(w); err != nil {
+		err := ui.Run(w)
+		cancel()
+		if err != nil {
 			log.Fatal(err)
 		}
 		os.Exit(0)
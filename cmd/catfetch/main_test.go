@@ -70,10 +70,10 @@ func TestMain_WindowCreation(t *testing.T) {
 	})
 
 	t.Run("sets_window_size", func(t *testing.T) {
-		// Window size is set to 400x500 dp
+		// Window size is set to 480x800 dp
 		testutil.AssertNoPanic(t, func() {
 			w := new(app.Window)
-			w.Option(app.Size(400, 500))
+			w.Option(app.Size(480, 800))
 		}, "setting window size should not panic")
 	})
 }
@@ -144,8 +144,8 @@ func TestMain_UnitPackage(t *testing.T) {
 		// This provides device-independent pixels for window size
 
 		testutil.AssertNoPanic(t, func() {
-			// unit.Dp(400) creates a 400dp value
-			// unit.Dp(500) creates a 500dp value
+			// unit.Dp(480) creates a 480dp value
+			// unit.Dp(800) creates a 800dp value
 		}, "unit.Dp should be available")
 	})
 }
@@ -161,13 +161,13 @@ func TestMain_WindowOptions(t *testing.T) {
 	})
 
 	t.Run("size_option", func(t *testing.T) {
-		// app.Size(unit.Dp(400), unit.Dp(500)) sets window size
-		// Width: 400dp, Height: 500dp
+		// app.Size(unit.Dp(480), unit.Dp(800)) sets window size
+		// Width: 480dp, Height: 800dp
 		testutil.AssertNoPanic(t, func() {
 			w := new(app.Window)
 			testutil.AssertNotNil(t, w, "window should be created")
 			// Note: app.Size may take unit.Dp or int depending on version
-			// The actual code uses: app.Size(unit.Dp(400), unit.Dp(500))
+			// The actual code uses: app.Size(unit.Dp(480), unit.Dp(800))
 		}, "size option should work")
 	})
 }
@@ -273,8 +273,8 @@ func TestMain_CodeStructure(t *testing.T) {
 // TestMain_WindowConfiguration tests window configuration
 func TestMain_WindowConfiguration(t *testing.T) {
 	t.Run("window_dimensions", func(t *testing.T) {
-		// Width: 400dp
-		// Height: 500dp
+		// Width: 480dp
+		// Height: 800dp, the tag and says panels take about 400dp
 		// Portrait orientation suitable for cat images
 	})
 
@@ -323,7 +323,7 @@ func TestMain_Documentation(t *testing.T) {
 		// 1. Launch UI goroutine:
 		//    - Create a new window
 		//    - Set window title to "CatFetch"
-		//    - Set window size to 400x500 dp
+		//    - Set window size to 480x800 dp
 		//    - Call ui.Run(w) to start UI event loop
 		//    - Handle errors from ui.Run
 		//    - Exit with os.Exit(0) when done
@@ -5,13 +5,14 @@ go 1.25
 require (
 	gioui.org v0.9.0
 	github.com/g4s8/hexcolor v1.2.0
+	golang.org/x/image v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	gioui.org/shader v1.0.8 // indirect
 	github.com/go-text/typesetting v0.3.0 // indirect
 	golang.org/x/exp/shiny v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
-	golang.org/x/image v0.26.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.24.0 // indirect
 )
@@ -5,9 +5,49 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
+	"testing"
 	"time"
 )
 
+// Routes maps ServeMux patterns, like "/cat/{id}" or "/api/tags", to the
+// handlers answering them. "/" answers every request no other route matches.
+type Routes map[string]http.HandlerFunc
+
+// NewMockCatAPI creates a test HTTP server standing in for the whole cat API
+// on one host, answering requests with the handlers of routes. The server is
+// closed when the test ends.
+func NewMockCatAPI(t *testing.T, routes Routes) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Counted wraps handler to add each request it answers to calls
+func Counted(calls *atomic.Int32, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}
+}
+
+// ServeJSON answers a request with body as JSON
+func ServeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(body))
+}
+
+// ServeImage answers a request with data as an image of mimeType
+func ServeImage(w http.ResponseWriter, mimeType string, data []byte) {
+	w.Header().Set("Content-Type", mimeType)
+	w.Write(data)
+}
+
 // ServerConfig configures the behavior of the mock HTTP server
 type ServerConfig struct {
 	MetadataResponse   string        // JSON to return for metadata
@@ -183,3 +183,126 @@ func CreateTestImageBytes(width, height int, format string) ([]byte, error) {
 	img := CreateColorImage(width, height, 100, 150, 200)
 	return EncodeImage(img, format)
 }
+
+// AnimatedGIFBytes encodes a width x height GIF with one solid colour frame
+// per entry in colors, each shown for delay hundredths of a second
+func AnimatedGIFBytes(width, height, delay int, disposal byte, colors ...color.RGBA) ([]byte, error) {
+	anim := &gif.GIF{}
+	for _, c := range colors {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{color.Transparent, c})
+		for i := range frame.Pix {
+			frame.Pix[i] = 1
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, disposal)
+	}
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, anim)
+	return buf.Bytes(), err
+}
+
+// bitWriter writes the least significant bit first, as VP8L expects
+type bitWriter struct {
+	buf   []byte
+	nbits uint
+}
+
+func (w *bitWriter) write(value uint32, n uint) {
+	for i := uint(0); i < n; i++ {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte((value>>i)&1) << (w.nbits % 8)
+		w.nbits++
+	}
+}
+
+// solidVP8L encodes a lossless WebP bitstream of a single colour. Every
+// prefix code holds one symbol, so the pixels themselves take no bits.
+func solidVP8L(width, height int, c color.NRGBA) []byte {
+	var w bitWriter
+	w.write(0x2f, 8) // signature
+	w.write(uint32(width-1), 14)
+	w.write(uint32(height-1), 14)
+	w.write(1, 1) // alpha is used
+	w.write(0, 3) // version
+	w.write(0, 1) // no transforms
+	w.write(0, 1) // no colour cache
+	w.write(0, 1) // no meta prefix codes
+	for _, symbol := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		w.write(1, 1) // simple code
+		w.write(0, 1) // one symbol
+		w.write(1, 1) // 8 bit symbol
+		w.write(uint32(symbol), 8)
+	}
+	return w.buf
+}
+
+func riffChunk(buf *bytes.Buffer, id string, data []byte) {
+	buf.WriteString(id)
+	buf.Write([]byte{byte(len(data)), byte(len(data) >> 8), byte(len(data) >> 16), byte(len(data) >> 24)})
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+func uint24LE(v int) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16)}
+}
+
+// WebPBytes encodes a lossless still WebP of a single colour
+func WebPBytes(width, height int, c color.NRGBA) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	riffChunk(&body, "VP8L", solidVP8L(width, height, c))
+
+	var buf bytes.Buffer
+	riffChunk(&buf, "RIFF", body.Bytes())
+	return buf.Bytes()
+}
+
+// WebPFrame is one frame of AnimatedWebPBytes. X and Y must be even.
+type WebPFrame struct {
+	X, Y, Width, Height int
+	Color               color.NRGBA
+	Duration            int  // milliseconds
+	NoBlend             bool // replace the canvas instead of alpha blending
+	Dispose             bool // clear the frame's area after it is shown
+}
+
+// AnimatedWebPBytes encodes an animated WebP with a width x height canvas,
+// played loops times (0 for forever)
+func AnimatedWebPBytes(width, height, loops int, frames ...WebPFrame) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+
+	vp8x := append([]byte{1<<1 | 1<<4, 0, 0, 0}, uint24LE(width-1)...)
+	riffChunk(&body, "VP8X", append(vp8x, uint24LE(height-1)...))
+	riffChunk(&body, "ANIM", []byte{0, 0, 0, 0, byte(loops), byte(loops >> 8)})
+
+	for _, f := range frames {
+		var anmf bytes.Buffer
+		anmf.Write(uint24LE(f.X / 2))
+		anmf.Write(uint24LE(f.Y / 2))
+		anmf.Write(uint24LE(f.Width - 1))
+		anmf.Write(uint24LE(f.Height - 1))
+		anmf.Write(uint24LE(f.Duration))
+		var flags byte
+		if f.NoBlend {
+			flags |= 1 << 1
+		}
+		if f.Dispose {
+			flags |= 1
+		}
+		anmf.WriteByte(flags)
+		riffChunk(&anmf, "VP8L", solidVP8L(f.Width, f.Height, f.Color))
+		riffChunk(&body, "ANMF", anmf.Bytes())
+	}
+
+	var buf bytes.Buffer
+	riffChunk(&buf, "RIFF", body.Bytes())
+	return buf.Bytes()
+}
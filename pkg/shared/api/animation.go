@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"time"
+)
+
+// DefaultFrameDelay replaces frame delays of 10ms or less, which browsers
+// treat as unset rather than playing the frames as fast as possible
+const DefaultFrameDelay = 100 * time.Millisecond
+
+// Animation is a decoded animated image. Frames are fully composited, with
+// each frame's disposal already applied, so any frame can be shown on its
+// own. It implements image.Image by showing the first frame, so callers that
+// don't animate still get a picture.
+type Animation struct {
+	Frames    []*image.RGBA
+	Delays    []time.Duration
+	Disposals []byte // the source disposal method of each frame, e.g. gif.DisposalBackground
+	LoopCount int    // as in gif.GIF: 0 loops forever, -1 plays once, n plays n+1 times
+}
+
+func (a *Animation) ColorModel() color.Model { return color.RGBAModel }
+
+func (a *Animation) Bounds() image.Rectangle { return a.Frames[0].Bounds() }
+
+func (a *Animation) At(x, y int) color.Color { return a.Frames[0].At(x, y) }
+
+func (a *Animation) FrameCount() int {
+	return len(a.Frames)
+}
+
+func (a *Animation) Frame(i int) image.Image {
+	return a.Frames[i]
+}
+
+func (a *Animation) FrameDelay(i int) time.Duration {
+	if a.Delays[i] <= 10*time.Millisecond {
+		return DefaultFrameDelay
+	}
+	return a.Delays[i]
+}
+
+// PlayCount is how many times the animation plays, 0 meaning forever
+func (a *Animation) PlayCount() int {
+	switch {
+	case a.LoopCount == 0:
+		return 0
+	case a.LoopCount < 0:
+		return 1
+	}
+	return a.LoopCount + 1
+}
+
+// Duration is the length of a single play
+func (a *Animation) Duration() time.Duration {
+	var total time.Duration
+	for i := range a.Frames {
+		total += a.FrameDelay(i)
+	}
+	return total
+}
+
+// decodeGIF decodes every frame of a GIF. A single frame GIF is returned as
+// a plain image, anything longer as an *Animation. The frames are counted
+// before decoding, so an animation over the limits fails without allocating
+// them.
+func decodeGIF(data []byte, limits DecodeLimits) (image.Image, error) {
+	if err := limits.checkFrames(gifFrames(data)); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 1 {
+		return g.Image[0], nil
+	}
+	return newGIFAnimation(g), nil
+}
+
+func gifCanvas(g *gif.GIF) image.Rectangle {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		// some encoders leave the logical screen size unset
+		for _, frame := range g.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+	return bounds
+}
+
+// gifFrames walks the blocks of a GIF without decoding any pixels, returning
+// the canvas as gifCanvas would and the number of frames. It stops at the
+// first thing it doesn't understand, leaving errors to gif.DecodeAll.
+func gifFrames(data []byte) (canvas image.Rectangle, frames int) {
+	const (
+		headerLen      = 6 // "GIF89a"
+		screenLen      = 7 // width, height, flags, background and aspect ratio
+		descriptorLen  = 9 // left, top, width, height and flags
+		colorTableFlag = 0x80
+	)
+	// colorTable is the length of the color table flags announce
+	colorTable := func(flags byte) int {
+		if flags&colorTableFlag == 0 {
+			return 0
+		}
+		return 3 << (flags&7 + 1)
+	}
+	// skipSubBlocks returns the offset after the sub-blocks starting at i
+	skipSubBlocks := func(i int) int {
+		for i < len(data) && data[i] != 0 {
+			i += int(data[i]) + 1
+		}
+		return i + 1
+	}
+
+	if len(data) < headerLen+screenLen {
+		return image.Rectangle{}, 0
+	}
+	screen := data[headerLen:]
+	canvas = image.Rect(0, 0, int(binary.LittleEndian.Uint16(screen[0:])), int(binary.LittleEndian.Uint16(screen[2:])))
+	unsized := canvas.Empty()
+
+	for i := headerLen + screenLen + colorTable(screen[4]); i < len(data); {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i = skipSubBlocks(i + 2)
+		case 0x2c: // image descriptor, then LZW code size and sub-blocks
+			if i+1+descriptorLen > len(data) {
+				return canvas, frames
+			}
+			d := data[i+1:]
+			frames++
+			if unsized {
+				left, top := int(binary.LittleEndian.Uint16(d[0:])), int(binary.LittleEndian.Uint16(d[2:]))
+				width, height := int(binary.LittleEndian.Uint16(d[4:])), int(binary.LittleEndian.Uint16(d[6:]))
+				canvas = canvas.Union(image.Rect(left, top, left+width, top+height))
+			}
+			i = skipSubBlocks(i + 1 + descriptorLen + colorTable(d[8]) + 1)
+		default: // the trailer, or something gif.DecodeAll will reject
+			return canvas, frames
+		}
+	}
+	return canvas, frames
+}
+
+func newGIFAnimation(g *gif.GIF) *Animation {
+	bounds := gifCanvas(g)
+
+	a := &Animation{
+		Frames:    make([]*image.RGBA, len(g.Image)),
+		Delays:    make([]time.Duration, len(g.Image)),
+		Disposals: make([]byte, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+
+	canvas := image.NewRGBA(bounds)
+	var previous *image.RGBA
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if i < len(g.Delay) {
+			a.Delays[i] = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		a.Disposals[i] = disposal
+
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		a.Frames[i] = cloneRGBA(canvas)
+
+		// prepare the canvas for the next frame
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return a
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := *src
+	dst.Pix = bytes.Clone(src.Pix)
+	return &dst
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+var (
+	red   = color.RGBA{R: 255, A: 255}
+	green = color.RGBA{G: 255, A: 255}
+	blue  = color.RGBA{B: 255, A: 255}
+)
+
+// solidFrame returns a paletted frame covering r in a single colour
+func solidFrame(r image.Rectangle, c color.Color) *image.Paletted {
+	frame := image.NewPaletted(r, color.Palette{color.Transparent, c})
+	for i := range frame.Pix {
+		frame.Pix[i] = 1
+	}
+	return frame
+}
+
+// TestDecodeGIF tests decoding single and multi frame GIFs
+func TestDecodeGIF(t *testing.T) {
+	t.Run("animated", func(t *testing.T) {
+		data, err := testutil.AnimatedGIFBytes(4, 4, 20, gif.DisposalNone, red, green, blue)
+		testutil.AssertNoError(t, err, "encode")
+
+		img, err := decodeGIF(data, DecodeLimits{})
+		testutil.AssertNoError(t, err, "decodeGIF should succeed")
+
+		anim, ok := img.(*Animation)
+		testutil.AssertTrue(t, ok, "should be an *Animation")
+		testutil.AssertEqual(t, 3, anim.FrameCount(), "frame count")
+		testutil.AssertEqual(t, 200*time.Millisecond, anim.FrameDelay(1), "delay")
+		testutil.AssertEqual(t, 600*time.Millisecond, anim.Duration(), "duration")
+		testutil.AssertEqual(t, color.Color(green), anim.Frame(1).At(2, 2), "second frame colour")
+		testutil.AssertEqual(t, color.Color(red), anim.At(2, 2), "image.Image shows the first frame")
+		testutil.AssertImageDimensions(t, anim, 4, 4)
+	})
+
+	t.Run("single_frame", func(t *testing.T) {
+		img, err := decodeGIF(testutil.ValidGIFBytes(), DecodeLimits{})
+		testutil.AssertNoError(t, err, "decodeGIF should succeed")
+		_, ok := img.(*Animation)
+		testutil.AssertFalse(t, ok, "a single frame is a plain image")
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		_, err := decodeGIF([]byte("GIF89a not really"), DecodeLimits{})
+		testutil.AssertError(t, err, "decodeGIF should fail")
+	})
+
+	t.Run("repo_cat_gif", func(t *testing.T) {
+		data, err := os.ReadFile("../../../cat.gif")
+		if err != nil {
+			t.Skip("cat.gif not available")
+		}
+		img, err := decodeGIF(data, DecodeLimits{})
+		testutil.AssertNoError(t, err, "decodeGIF should succeed")
+		anim, ok := img.(*Animation)
+		testutil.AssertTrue(t, ok, "cat.gif is animated")
+		testutil.AssertEqual(t, 50, anim.FrameCount(), "frame count")
+		testutil.AssertEqual(t, 70*time.Millisecond, anim.FrameDelay(0), "delay")
+		testutil.AssertImageDimensions(t, anim, 400, 225)
+	})
+}
+
+// TestGIFFrames tests counting frames without decoding them
+func TestGIFFrames(t *testing.T) {
+	data, err := testutil.AnimatedGIFBytes(4, 4, 20, gif.DisposalNone, red, green, blue)
+	testutil.AssertNoError(t, err, "encode")
+
+	t.Run("animated", func(t *testing.T) {
+		canvas, frames := gifFrames(data)
+		testutil.AssertEqual(t, image.Rect(0, 0, 4, 4), canvas, "canvas")
+		testutil.AssertEqual(t, 3, frames, "frames")
+	})
+
+	t.Run("unsized_canvas", func(t *testing.T) {
+		unsized := append([]byte(nil), data...)
+		copy(unsized[6:10], []byte{0, 0, 0, 0})
+		canvas, frames := gifFrames(unsized)
+		testutil.AssertEqual(t, image.Rect(0, 0, 4, 4), canvas, "canvas from the frames")
+		testutil.AssertEqual(t, 3, frames, "frames")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, frames := gifFrames(data[:len(data)/2])
+		testutil.AssertTrue(t, frames < 3, "only the frames present are counted")
+		_, frames = gifFrames(data[:8])
+		testutil.AssertEqual(t, 0, frames, "no frames in a header")
+	})
+
+	t.Run("repo_cat_gif", func(t *testing.T) {
+		data, err := os.ReadFile("../../../cat.gif")
+		if err != nil {
+			t.Skip("cat.gif not available")
+		}
+		canvas, frames := gifFrames(data)
+		testutil.AssertEqual(t, image.Rect(0, 0, 400, 225), canvas, "canvas")
+		testutil.AssertEqual(t, 50, frames, "frames")
+	})
+
+	t.Run("checked_before_decoding", func(t *testing.T) {
+		// an 8x8 GIF with two frames whose pixel data is garbage, which
+		// gif.DecodeAll would fail on if it got that far
+		bomb := []byte("GIF89a\x08\x00\x08\x00\x00\x00\x00")
+		for range 2 {
+			bomb = append(bomb, 0x2c, 0, 0, 0, 0, 8, 0, 8, 0, 0, 2, 2, 0xff, 0xff, 0)
+		}
+		bomb = append(bomb, 0x3b)
+
+		_, err := decodeGIF(bomb, DecodeLimits{MaxPixels: 100})
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+		_, err = decodeGIF(bomb, DecodeLimits{MaxPixels: 128})
+		testutil.AssertFalse(t, errors.Is(err, ErrImageTooLarge), "within the limit, decoding fails on the pixels")
+		testutil.AssertError(t, err, "garbage pixels")
+	})
+}
+
+// TestGIFAnimation_Disposal tests that frames are composited per disposal method
+func TestGIFAnimation_Disposal(t *testing.T) {
+	full := image.Rect(0, 0, 4, 4)
+	corner := image.Rect(0, 0, 2, 2)
+	transparent := color.RGBA{}
+
+	tests := []struct {
+		name     string
+		disposal byte
+		// what is left of the green corner once the third frame is drawn
+		leftover color.RGBA
+	}{
+		{"none_keeps_drawing", gif.DisposalNone, green},
+		{"background_clears", gif.DisposalBackground, transparent},
+		{"previous_restores", gif.DisposalPrevious, red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// red background, then a green 2x2 corner with the disposal
+			// under test, then a blue 1x1 corner
+			g := &gif.GIF{
+				Image:    []*image.Paletted{solidFrame(full, red), solidFrame(corner, green), solidFrame(image.Rect(0, 0, 1, 1), blue)},
+				Delay:    []int{1, 1, 1},
+				Disposal: []byte{gif.DisposalNone, tt.disposal, gif.DisposalNone},
+				Config:   image.Config{Width: 4, Height: 4},
+			}
+			anim := newGIFAnimation(g)
+
+			testutil.AssertEqual(t, color.Color(green), anim.Frames[1].At(1, 1), "second frame corner")
+			testutil.AssertEqual(t, color.Color(blue), anim.Frames[2].At(0, 0), "third frame pixel")
+			testutil.AssertEqual(t, color.Color(tt.leftover), anim.Frames[2].At(1, 1), "rest of the corner")
+			testutil.AssertEqual(t, color.Color(red), anim.Frames[2].At(3, 3), "outside the corner")
+			testutil.AssertEqual(t, tt.disposal, anim.Disposals[1], "disposal kept")
+		})
+	}
+
+	t.Run("background_shows_through", func(t *testing.T) {
+		// the background disposal of a full frame leaves a transparent
+		// canvas, so a partial next frame has nothing behind it
+		g := &gif.GIF{
+			Image:    []*image.Paletted{solidFrame(full, red), solidFrame(corner, green)},
+			Delay:    []int{1, 1},
+			Disposal: []byte{gif.DisposalBackground, gif.DisposalNone},
+			Config:   image.Config{Width: 4, Height: 4},
+		}
+		anim := newGIFAnimation(g)
+		testutil.AssertEqual(t, color.Color(transparent), anim.Frames[1].At(3, 3), "cleared outside the corner")
+		testutil.AssertEqual(t, color.Color(red), anim.Frames[0].At(3, 3), "earlier frame untouched")
+	})
+
+	t.Run("missing_screen_size", func(t *testing.T) {
+		g := &gif.GIF{
+			Image: []*image.Paletted{solidFrame(corner, red), solidFrame(image.Rect(2, 2, 3, 3), green)},
+			Delay: []int{1, 1},
+		}
+		anim := newGIFAnimation(g)
+		testutil.AssertEqual(t, image.Rect(0, 0, 3, 3), anim.Bounds(), "bounds from the frames")
+	})
+}
+
+// TestAnimation_Timing tests delays and play counts
+func TestAnimation_Timing(t *testing.T) {
+	anim := &Animation{
+		Frames: []*image.RGBA{image.NewRGBA(image.Rect(0, 0, 1, 1)), image.NewRGBA(image.Rect(0, 0, 1, 1))},
+		Delays: []time.Duration{0, 10 * time.Millisecond},
+	}
+	testutil.AssertEqual(t, DefaultFrameDelay, anim.FrameDelay(0), "unset delay")
+	testutil.AssertEqual(t, DefaultFrameDelay, anim.FrameDelay(1), "10ms delay")
+
+	for loopCount, plays := range map[int]int{0: 0, -1: 1, 2: 3} {
+		anim.LoopCount = loopCount
+		testutil.AssertEqual(t, plays, anim.PlayCount(), "play count")
+	}
+}
+
+// TestClient_Fetch_AnimatedGIF tests that Fetch keeps every frame of a GIF
+func TestClient_Fetch_AnimatedGIF(t *testing.T) {
+	data, err := testutil.AnimatedGIFBytes(8, 8, 5, gif.DisposalNone, red, green)
+	testutil.AssertNoError(t, err, "encode")
+	server := newStandInServer(t, "image/gif", data)
+
+	img, meta, err := NewClient().WithBaseURL(server.URL).CatByID(context.Background(), "animated")
+	testutil.AssertNoError(t, err, "CatByID should succeed")
+	testutil.AssertEqual(t, "image/gif", meta.GetMIMEType(), "MIME type")
+
+	anim, ok := img.(*Animation)
+	testutil.AssertTrue(t, ok, "should be an *Animation")
+	testutil.AssertEqual(t, 2, anim.FrameCount(), "frame count")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"image"
+	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
@@ -229,3 +230,112 @@ func FuzzImageBoundsCalculation(f *testing.F) {
 		}
 	})
 }
+
+// FuzzParseCatURLRoundTrip fuzzes that Parse(Generate(x)) generates the same URL again
+func FuzzParseCatURLRoundTrip(f *testing.F) {
+	f.Add("", "", 0, 0, 0, 0, false)
+	f.Add("abc123", "", 300, 2, 0, 0, false)
+	f.Add("", "Hello World!", 0, 0, 3, 40, true)
+	f.Add("id/with/slashes", "a/b?c=d&e#f", 10, 5, 1, 1, false)
+	f.Add("orange", "100% cat", -1, 7, 8, 0, true)
+
+	f.Fuzz(func(t *testing.T, id, says string, width, filter, font, fontSize int, custom bool) {
+		old := AvailableTags
+		AvailableTags = NewTagRegistry(0, "")
+		AvailableTags.Set(CAASTags{"orange", "cute"})
+		defer func() { AvailableTags = old }()
+
+		u := NewCatURL()
+		if id != "" {
+			u = u.WithID(id)
+		}
+		if says != "" {
+			u = u.WithSays(says).WithFont(CAASFont(font)).WithFontSize(fontSize)
+		}
+		if width != 0 {
+			u = u.WithWidth(width)
+		}
+		u = u.WithCAASImageFilter(CAASImageFilter(filter))
+		if custom {
+			u = u.WithCAASImageFilter(CAASImageFilterCustom).WithFilterRGB(width&0xff, fontSize&0xff, 10).WithHue(width)
+		}
+
+		generated, err := u.Generate()
+		if err != nil {
+			// invalid combinations are rejected before parsing
+			return
+		}
+
+		parsed, err := ParseCatURL(generated)
+		if err != nil {
+			t.Fatalf("ParseCatURL(%q) failed: %v", generated, err)
+		}
+		regenerated, err := parsed.Generate()
+		if err != nil {
+			t.Fatalf("Generate after parse of %q failed: %v", generated, err)
+		}
+		if regenerated != generated {
+			t.Errorf("round trip mismatch: %q != %q", regenerated, generated)
+		}
+	})
+}
+
+// FuzzParseCatURL fuzzes parsing of arbitrary strings
+func FuzzParseCatURL(f *testing.F) {
+	f.Add("https://cataas.com/cat")
+	f.Add("https://cataas.com/cat/abc/says/hi%2Fthere?fontSize=20&width=10")
+	f.Add("https://cataas.com/cat?filter=custom&r=10&hue=-20&json=true")
+	f.Add("http://localhost:8080/prefix/cat/says/")
+	f.Add("not a url")
+	f.Add("https://cataas.com/dog")
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		// Parsing should never panic
+		u, err := ParseCatURL(raw)
+		if err != nil {
+			return
+		}
+
+		// Anything that parses must generate, and parse back to the same URL
+		generated, err := u.Generate()
+		if err != nil {
+			t.Fatalf("Generate after parse of %q failed: %v", raw, err)
+		}
+		again, err := ParseCatURL(generated)
+		if err != nil {
+			t.Fatalf("ParseCatURL(%q) failed: %v", generated, err)
+		}
+		regenerated, err := again.Generate()
+		if err != nil {
+			t.Fatalf("Generate after reparse of %q failed: %v", generated, err)
+		}
+		if regenerated != generated {
+			t.Errorf("round trip mismatch: %q != %q", regenerated, generated)
+		}
+	})
+}
+
+// FuzzDecode fuzzes decoder selection and the decoders behind it
+func FuzzDecode(f *testing.F) {
+	f.Add("image/png", testutil.ValidPNGBytes())
+	f.Add("image/gif", testutil.ValidGIFBytes())
+	f.Add("image/jpeg", testutil.ValidJPEGBytes())
+	f.Add("image/webp", testutil.WebPBytes(2, 2, color.NRGBA{R: 255, A: 255}))
+	f.Add("image/webp", testutil.AnimatedWebPBytes(2, 2, 0,
+		testutil.WebPFrame{Width: 2, Height: 2, Color: color.NRGBA{R: 255, A: 255}, Duration: 50},
+		testutil.WebPFrame{Width: 1, Height: 1, Color: color.NRGBA{B: 255, A: 255}, Duration: 50},
+	))
+	f.Add("", testutil.CorruptedImageBytes())
+
+	limits := DecodeLimits{MaxBytes: 1 << 20, MaxPixels: 1 << 20}
+	f.Fuzz(func(t *testing.T, mimeType string, data []byte) {
+		// Decoding should never panic, and never return an image with an error
+		img, _, err := Decode(mimeType, data, limits)
+		if err == nil && img == nil {
+			t.Fatalf("Decode(%q) returned neither an image nor an error", mimeType)
+		}
+		if err != nil && img != nil {
+			t.Fatalf("Decode(%q) returned an image and %v", mimeType, err)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -112,13 +113,13 @@ func TestRequestRandomCat_RealFunction_MetadataFetchError(t *testing.T) {
 	// Call the actual function
 	img, meta, err := RequestRandomCat(5 * time.Second)
 
-	// Should get error (but might not - HTTP 500 returns a body that json.Decode will fail on)
-	// The function doesn't check HTTP status codes, only JSON decode errors
-	_ = img
-	_ = meta
-	_ = err
-	// This test demonstrates that RequestRandomCat doesn't check HTTP status codes
-	// It only fails if JSON decode fails
+	// The status is checked before decoding, so the error carries the status code
+	var statusErr *HTTPStatusError
+	testutil.AssertTrue(t, errors.As(err, &statusErr), "should fail with *HTTPStatusError")
+	testutil.AssertEqual(t, http.StatusInternalServerError, statusErr.StatusCode, "status code")
+	testutil.AssertEqual(t, "Server error", statusErr.Body, "body snippet")
+	testutil.AssertNil(t, img, "image should be nil on error")
+	testutil.AssertNil(t, meta, "metadata should be nil on error")
 }
 
 // TestRequestRandomCat_RealFunction_MalformedJSON tests JSON parsing errors
@@ -183,8 +184,8 @@ func TestRequestRandomCat_RealFunction_ImageFetchError(t *testing.T) {
 	// Call the actual function
 	img, meta, err := RequestRandomCat(5 * time.Second)
 
-	// Should fail when trying to decode the image (404 response isn't a valid image)
-	testutil.AssertError(t, err, "should fail with bad image data")
+	// Should fail on the 404 status before trying to decode the image
+	testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "should fail with a status error")
 	testutil.AssertNil(t, img, "image should be nil on error")
 	testutil.AssertNil(t, meta, "metadata should be nil on error")
 }
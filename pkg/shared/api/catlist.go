@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	caasCatsPath        = "/api/cats"
+	caasCountPath       = "/api/count"
+	DefaultCatsPageSize = 50
+)
+
+// catListEntry is one element of the /api/cats response. It differs from the
+// /cat?json=true metadata: there is no url, the timestamp is camel cased and
+// older servers name the id _id.
+type catListEntry struct {
+	ID        string    `json:"id"`
+	LegacyID  string    `json:"_id"`
+	Tags      []string  `json:"tags"`
+	MIMEType  string    `json:"mimetype"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (e catListEntry) metadata() CatMetadata {
+	id := e.ID
+	if id == "" {
+		id = e.LegacyID
+	}
+	return CatMetadata{
+		ID:        id,
+		Tags:      e.Tags,
+		CreatedAt: e.CreatedAt,
+		URL:       caasCatPath + "/" + url.PathEscape(id),
+		MIMEType:  e.MIMEType,
+	}
+}
+
+// tagsQuery is the query shared by /api/cats and /api/count
+func tagsQuery(tags []string) url.Values {
+	query := url.Values{}
+	if len(tags) > 0 {
+		query.Set("tags", strings.Join(tags, ","))
+	}
+	return query
+}
+
+// ListCats returns up to limit cats carrying every one of tags, skipping the
+// first skip matches. A limit of 0 leaves the page size to the server.
+func (c *Client) ListCats(ctx context.Context, tags []string, skip, limit int) ([]CatMetadata, error) {
+	if skip < 0 {
+		return nil, ValidationError{Field: "skip", Value: strconv.Itoa(skip), Reason: "must be zero or a positive integer", Err: ErrInvalidOption}
+	}
+	if limit < 0 {
+		return nil, ValidationError{Field: "limit", Value: strconv.Itoa(limit), Reason: "must be zero or a positive integer", Err: ErrInvalidOption}
+	}
+
+	query := tagsQuery(tags)
+	query.Set("skip", strconv.Itoa(skip))
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var entries []catListEntry
+	if err := c.getJSON(ctx, c.baseURL+caasCatsPath+caasQueryStart+query.Encode(), &entries); err != nil {
+		return nil, err
+	}
+
+	cats := make([]CatMetadata, len(entries))
+	for i, entry := range entries {
+		cats[i] = entry.metadata()
+	}
+	return cats, nil
+}
+
+// Cats iterates over every cat carrying all of tags, fetching pageSize cats
+// at a time as the loop advances. A pageSize of 0 or less uses
+// DefaultCatsPageSize. The first error is yielded and ends the iteration.
+func (c *Client) Cats(ctx context.Context, tags []string, pageSize int) iter.Seq2[CatMetadata, error] {
+	if pageSize <= 0 {
+		pageSize = DefaultCatsPageSize
+	}
+	return func(yield func(CatMetadata, error) bool) {
+		for skip := 0; ; skip += pageSize {
+			page, err := c.ListCats(ctx, tags, skip, pageSize)
+			if err != nil {
+				yield(CatMetadata{}, fmt.Errorf("listing cats from %d: %w", skip, err))
+				return
+			}
+			for _, cat := range page {
+				if !yield(cat, nil) {
+					return
+				}
+			}
+			// a short page is the last one
+			if len(page) < pageSize {
+				return
+			}
+		}
+	}
+}
+
+// Count returns how many cats carry every one of tags, or the total number of
+// cats when tags is empty. Together with ListCats it lets callers pick a
+// random offset themselves.
+func (c *Client) Count(ctx context.Context, tags []string) (int, error) {
+	reqURL := c.baseURL + caasCountPath
+	if query := tagsQuery(tags); len(query) > 0 {
+		reqURL += caasQueryStart + query.Encode()
+	}
+
+	var resp struct {
+		Count *int `json:"count"`
+	}
+	if err := c.getJSON(ctx, reqURL, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Count == nil {
+		return 0, fmt.Errorf("%w: response has no count", ErrMetadataDecode)
+	}
+	return *resp.Count, nil
+}
+
+// ListCats lists cats using DefaultClient
+func ListCats(ctx context.Context, tags []string, skip, limit int) ([]CatMetadata, error) {
+	return DefaultClient.ListCats(ctx, tags, skip, limit)
+}
+
+// CountCats returns the number of cats tagged tag, or of all cats when tag is
+// empty, using AvailableTags' count cache and DefaultClient
+func CountCats(ctx context.Context, tag string) (int, error) {
+	return AvailableTags.Count(ctx, DefaultClient, tag)
+}
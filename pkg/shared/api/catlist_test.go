@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// newCatListServer serves /api/cats and /api/count from total generated cats,
+// every even one tagged "orange"
+func newCatListServer(t *testing.T, total int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	serve := testutil.Counted(&calls, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		skip, _ := strconv.Atoi(query.Get("skip"))
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil {
+			limit = 10
+		}
+		var want []string
+		if tags := query.Get("tags"); tags != "" {
+			want = strings.Split(tags, ",")
+		}
+
+		var matches []map[string]any
+		for i := range total {
+			tags := []string{"cat"}
+			if i%2 == 0 {
+				tags = append(tags, "orange")
+			}
+			if !containsAll(tags, want) {
+				continue
+			}
+			matches = append(matches, map[string]any{
+				"id":        fmt.Sprintf("cat%02d", i),
+				"tags":      tags,
+				"mimetype":  "image/jpeg",
+				"createdAt": "2025-01-01T12:00:00Z",
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == caasCountPath {
+			json.NewEncoder(w).Encode(map[string]int{"count": len(matches)})
+			return
+		}
+		matches = matches[min(skip, len(matches)):]
+		matches = matches[:min(limit, len(matches))]
+		json.NewEncoder(w).Encode(matches)
+	})
+	server := testutil.NewMockCatAPI(t, testutil.Routes{caasCatsPath: serve, caasCountPath: serve})
+	return server, &calls
+}
+
+func containsAll(have, want []string) bool {
+	for _, tag := range want {
+		if !slices.Contains(have, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestClient_ListCats tests a single page of the cat listing
+func TestClient_ListCats(t *testing.T) {
+	server, _ := newCatListServer(t, 25)
+	c := NewClient().WithBaseURL(server.URL)
+
+	t.Run("page", func(t *testing.T) {
+		cats, err := c.ListCats(context.Background(), nil, 5, 3)
+		testutil.AssertNoError(t, err, "ListCats should succeed")
+		testutil.AssertEqual(t, 3, len(cats), "page length")
+		testutil.AssertEqual(t, "cat05", cats[0].GetID(), "first ID")
+		testutil.AssertEqual(t, "/cat/cat05", cats[0].GetURL(), "URL")
+		testutil.AssertEqual(t, "image/jpeg", cats[0].GetMIMEType(), "MIME type")
+		testutil.AssertEqual(t, 2025, cats[0].GetCreatedAt().Year(), "createdAt")
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		cats, err := c.ListCats(context.Background(), []string{"orange", "cat"}, 0, 100)
+		testutil.AssertNoError(t, err, "ListCats should succeed")
+		testutil.AssertEqual(t, 13, len(cats), "tagged cats")
+		for _, cat := range cats {
+			testutil.AssertTrue(t, slices.Contains(cat.GetTags(), "orange"), cat.GetID()+" should be orange")
+		}
+	})
+
+	t.Run("past_the_end", func(t *testing.T) {
+		cats, err := c.ListCats(context.Background(), nil, 100, 10)
+		testutil.AssertNoError(t, err, "ListCats should succeed")
+		testutil.AssertEqual(t, 0, len(cats), "no cats")
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		_, err := c.ListCats(context.Background(), nil, -1, 10)
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidOption), "negative skip")
+		_, err = c.ListCats(context.Background(), nil, 0, -1)
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidOption), "negative limit")
+	})
+
+	t.Run("legacy_id", func(t *testing.T) {
+		legacy := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"_id":"old1","tags":["x"],"mimetype":"image/png"}]`))
+		}})
+
+		cats, err := NewClient().WithBaseURL(legacy.URL).ListCats(context.Background(), nil, 0, 0)
+		testutil.AssertNoError(t, err, "ListCats should succeed")
+		testutil.AssertEqual(t, "old1", cats[0].GetID(), "_id used")
+	})
+}
+
+// TestClient_Cats tests the paging iterator
+func TestClient_Cats(t *testing.T) {
+	t.Run("all_pages", func(t *testing.T) {
+		server, calls := newCatListServer(t, 25)
+		c := NewClient().WithBaseURL(server.URL)
+
+		var ids []string
+		for cat, err := range c.Cats(context.Background(), nil, 10) {
+			testutil.AssertNoError(t, err, "iteration should succeed")
+			ids = append(ids, cat.GetID())
+		}
+		testutil.AssertEqual(t, 25, len(ids), "every cat")
+		testutil.AssertEqual(t, "cat24", ids[24], "last cat")
+		testutil.AssertEqual(t, int32(3), calls.Load(), "three pages")
+	})
+
+	t.Run("exact_multiple", func(t *testing.T) {
+		server, calls := newCatListServer(t, 20)
+		c := NewClient().WithBaseURL(server.URL)
+
+		count := 0
+		for _, err := range c.Cats(context.Background(), nil, 10) {
+			testutil.AssertNoError(t, err, "iteration should succeed")
+			count++
+		}
+		testutil.AssertEqual(t, 20, count, "every cat")
+		testutil.AssertEqual(t, int32(3), calls.Load(), "an empty page ends the listing")
+	})
+
+	t.Run("early_break", func(t *testing.T) {
+		server, calls := newCatListServer(t, 100)
+		c := NewClient().WithBaseURL(server.URL)
+
+		count := 0
+		for range c.Cats(context.Background(), []string{"orange"}, 5) {
+			count++
+			if count == 7 {
+				break
+			}
+		}
+		testutil.AssertEqual(t, 7, count, "stopped at 7")
+		testutil.AssertEqual(t, int32(2), calls.Load(), "no pages fetched after break")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusForbidden)
+		}})
+		c := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry)
+
+		var errs []error
+		for _, err := range c.Cats(context.Background(), nil, 0) {
+			errs = append(errs, err)
+		}
+		testutil.AssertEqual(t, 1, len(errs), "one error yielded")
+		testutil.AssertTrue(t, errors.Is(errs[0], ErrHTTPStatus), "status error")
+	})
+}
+
+// TestClient_Count tests the cat count endpoint
+func TestClient_Count(t *testing.T) {
+	server, _ := newCatListServer(t, 25)
+	c := NewClient().WithBaseURL(server.URL)
+
+	total, err := c.Count(context.Background(), nil)
+	testutil.AssertNoError(t, err, "Count should succeed")
+	testutil.AssertEqual(t, 25, total, "all cats")
+
+	orange, err := c.Count(context.Background(), []string{"orange"})
+	testutil.AssertNoError(t, err, "Count should succeed")
+	testutil.AssertEqual(t, 13, orange, "orange cats")
+
+	t.Run("no_count_field", func(t *testing.T) {
+		server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"total":3}`))
+		}})
+
+		_, err := NewClient().WithBaseURL(server.URL).Count(context.Background(), nil)
+		testutil.AssertTrue(t, errors.Is(err, ErrMetadataDecode), "should be ErrMetadataDecode")
+	})
+}
@@ -1,46 +1,307 @@
 package api
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 )
 
 const (
-	caasTags = "https://cataas.com/api/tags?json=true" //will return valid tags
+	DefaultTagTTL    = 24 * time.Hour
+	tagCacheDirName  = "catfetch"
+	tagCacheFileName = "tags.json"
 )
 
-var AvailableTags = CAASTags{}
+var ErrNoTagCache = fmt.Errorf("no tag cache configured")
+
+// TagRetryInterval is how often AutoRefresh retries a failed first load
+var TagRetryInterval = time.Minute
+
+// AvailableTags holds the tags known to cataas.com. It is filled by
+// FetchCAASTags and consulted by CatURL.WithTag.
+var AvailableTags = NewTagRegistry(DefaultTagTTL, DefaultTagCachePath())
 
 type CAASTags []string
 
-func FetchCAASTags(timeout time.Duration) {
-	bodyReader := bytes.NewReader(make([]byte, 0))
-	client := &http.Client{Timeout: timeout}
-	req, err := http.NewRequest(http.MethodGet, caasTags, bodyReader)
+// TagRegistry is a concurrency safe store for the server's tag list. It can
+// be seeded from an on-disk cache so tags are usable before the network
+// answers, and refreshes itself once the list is older than its TTL.
+type TagRegistry struct {
+	mu        sync.RWMutex
+	tags      CAASTags
+	index     map[string]struct{}
+	updatedAt time.Time
+	ttl       time.Duration
+	cachePath string
+	ready     chan struct{}
+	readyOnce sync.Once
+	counts    map[string]tagCount // cats per tag, "" for all cats
+}
+
+type tagCount struct {
+	count     int
+	fetchedAt time.Time
+}
+
+// tagCacheFile is the on-disk format of the tag cache
+type tagCacheFile struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Tags      CAASTags  `json:"tags"`
+}
+
+// NewTagRegistry creates an empty registry. A zero ttl never goes stale and
+// an empty cachePath disables the disk cache.
+func NewTagRegistry(ttl time.Duration, cachePath string) *TagRegistry {
+	return &TagRegistry{
+		index:     make(map[string]struct{}),
+		counts:    make(map[string]tagCount),
+		ttl:       ttl,
+		cachePath: cachePath,
+		ready:     make(chan struct{}),
+	}
+}
+
+// DefaultTagCachePath returns the tag cache location in the user's cache
+// directory, or "" when there is none.
+func DefaultTagCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, tagCacheDirName, tagCacheFileName)
+}
+
+// Tags returns a copy of the current tag list
+func (r *TagRegistry) Tags() CAASTags {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.tags)
+}
+
+func (r *TagRegistry) Contains(tag string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.index[tag]
+	return ok
+}
+
+func (r *TagRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tags)
+}
+
+func (r *TagRegistry) UpdatedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.updatedAt
+}
+
+// Ready is closed the first time tags are loaded, from the cache or the network
+func (r *TagRegistry) Ready() <-chan struct{} {
+	return r.ready
+}
+
+func (r *TagRegistry) IsReady() bool {
+	select {
+	case <-r.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stale reports whether the tags are missing or older than the TTL
+func (r *TagRegistry) Stale() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.tags) == 0 {
+		return true
+	}
+	return r.ttl > 0 && time.Since(r.updatedAt) > r.ttl
+}
+
+// Set replaces the tag list
+func (r *TagRegistry) Set(tags CAASTags) {
+	r.set(tags, time.Now())
+}
+
+func (r *TagRegistry) set(tags CAASTags, updatedAt time.Time) {
+	index := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		index[tag] = struct{}{}
+	}
+
+	r.mu.Lock()
+	r.tags = slices.Clone(tags)
+	r.index = index
+	r.updatedAt = updatedAt
+	// drop counts for tags the server no longer has
+	for tag := range r.counts {
+		if _, ok := index[tag]; !ok && tag != "" {
+			delete(r.counts, tag)
+		}
+	}
+	r.mu.Unlock()
+
+	r.readyOnce.Do(func() { close(r.ready) })
+}
+
+// CachedCount returns the last fetched number of cats tagged tag ("" for all
+// cats) and whether it is still within the TTL
+func (r *TagRegistry) CachedCount(tag string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cached, ok := r.counts[tag]
+	if !ok {
+		return 0, false
+	}
+	return cached.count, r.ttl <= 0 || time.Since(cached.fetchedAt) <= r.ttl
+}
+
+// Count returns the number of cats tagged tag, or of all cats when tag is
+// empty. Counts are cached for the registry's TTL. Once the tag list has
+// loaded, unknown tags are rejected with ErrInvalidTag without a request.
+func (r *TagRegistry) Count(ctx context.Context, c *Client, tag string) (int, error) {
+	if count, fresh := r.CachedCount(tag); fresh {
+		return count, nil
+	}
+	if tag != "" && r.Len() > 0 && !r.Contains(tag) {
+		return 0, ValidationError{Field: "tag", Value: tag, Reason: "not a known cataas tag", Err: ErrInvalidTag}
+	}
+
+	var tags []string
+	if tag != "" {
+		tags = []string{tag}
+	}
+	count, err := c.Count(ctx, tags)
 	if err != nil {
-		fmt.Println(err)
+		return 0, err
 	}
 
-	resp, err := client.Do(req)
+	r.mu.Lock()
+	r.counts[tag] = tagCount{count: count, fetchedAt: time.Now()}
+	r.mu.Unlock()
+	return count, nil
+}
+
+// Refresh fetches the tag list from the server and writes it to the disk cache
+func (r *TagRegistry) Refresh(ctx context.Context, c *Client) error {
+	tags, err := c.Tags(ctx)
 	if err != nil {
-		fmt.Println(err)
+		return err
+	}
+	r.Set(tags)
+
+	if err := r.SaveCache(); err != nil && !errors.Is(err, ErrNoTagCache) {
+		log.Printf("Error saving tag cache: %v", err)
+	}
+	return nil
+}
+
+// Load seeds the registry from the disk cache and refreshes it from the
+// server when the cached list is missing or stale. If the refresh fails but
+// cached tags were loaded, the registry stays usable and the error is still
+// returned.
+func (r *TagRegistry) Load(ctx context.Context, c *Client) error {
+	if r.Len() == 0 {
+		if err := r.LoadCache(); err != nil && !errors.Is(err, ErrNoTagCache) && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error loading tag cache: %v", err)
+		}
+	}
+	if !r.Stale() {
+		return nil
+	}
+	return r.Refresh(ctx, c)
+}
+
+// AutoRefresh loads the tags and then refreshes them every TTL until ctx is
+// done. Until a load succeeds it is retried every TagRetryInterval, so a
+// startup without network doesn't leave the registry empty for a whole TTL.
+func (r *TagRegistry) AutoRefresh(ctx context.Context, c *Client) {
+	for {
+		err := r.Load(ctx, c)
+		if err == nil || r.Len() > 0 {
+			if err != nil {
+				log.Printf("Error loading tags: %v", err)
+			}
+			break
+		}
+		log.Printf("Error loading tags, trying again in %v: %v", TagRetryInterval, err)
+		timer := time.NewTimer(TagRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	if r.ttl <= 0 {
+		return
 	}
-	// clean up when done
-	defer func(body io.ReadCloser) {
-		err := body.Close()
-		if err != nil {
-			log.Println(err)
+
+	ticker := time.NewTicker(r.ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx, c); err != nil {
+				log.Printf("Error refreshing tags: %v", err)
+			}
 		}
-	}(resp.Body)
+	}
+}
 
-	err = json.NewDecoder(resp.Body).Decode(&AvailableTags)
+// LoadCache reads the tag list from the disk cache
+func (r *TagRegistry) LoadCache() error {
+	if r.cachePath == "" {
+		return ErrNoTagCache
+	}
+	data, err := os.ReadFile(r.cachePath)
 	if err != nil {
-		fmt.Println(err)
+		return err
+	}
+	var cached tagCacheFile
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return fmt.Errorf("%s: %w", r.cachePath, err)
 	}
+	if len(cached.Tags) == 0 {
+		return nil
+	}
+	r.set(cached.Tags, cached.UpdatedAt)
+	return nil
+}
+
+// SaveCache writes the tag list to the disk cache
+func (r *TagRegistry) SaveCache() error {
+	if r.cachePath == "" {
+		return ErrNoTagCache
+	}
+
+	r.mu.RLock()
+	data, err := json.Marshal(tagCacheFile{UpdatedAt: r.updatedAt, Tags: r.tags})
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.cachePath, data)
+}
 
+// FetchCAASTags loads the tags known to cataas.com into AvailableTags once,
+// using the disk cache when it is still fresh. Programs that keep running,
+// like the GUI, run AvailableTags.AutoRefresh instead.
+func FetchCAASTags(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return AvailableTags.Load(ctx, NewClient())
 }
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// newTagServer serves tags and counts how often it was asked
+func newTagServer(t *testing.T, body string, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": testutil.Counted(&calls, func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, "no tags for you", status)
+			return
+		}
+		testutil.ServeJSON(w, body)
+	})})
+	return server, &calls
+}
+
+func writeTagCache(t *testing.T, path string, updatedAt time.Time, tags CAASTags) {
+	t.Helper()
+	data, err := json.Marshal(tagCacheFile{UpdatedAt: updatedAt, Tags: tags})
+	testutil.AssertNoError(t, err, "marshal cache")
+	testutil.AssertNoError(t, os.WriteFile(path, data, 0o644), "write cache")
+}
+
+// TestTagRegistry_Set tests replacing and reading the tag list
+func TestTagRegistry_Set(t *testing.T) {
+	r := NewTagRegistry(time.Hour, "")
+
+	testutil.AssertFalse(t, r.IsReady(), "empty registry should not be ready")
+	testutil.AssertTrue(t, r.Stale(), "empty registry should be stale")
+	testutil.AssertFalse(t, r.Contains("cute"), "empty registry contains nothing")
+
+	r.Set(CAASTags{"cute", "orange"})
+
+	testutil.AssertTrue(t, r.IsReady(), "should be ready after Set")
+	testutil.AssertFalse(t, r.Stale(), "fresh tags should not be stale")
+	testutil.AssertTrue(t, r.Contains("orange"), "should contain orange")
+	testutil.AssertFalse(t, r.Contains("dog"), "should not contain dog")
+	testutil.AssertEqual(t, 2, r.Len(), "length")
+
+	tags := r.Tags()
+	tags[0] = "changed"
+	testutil.AssertTrue(t, r.Contains("cute"), "Tags should return a copy")
+
+	r.Set(CAASTags{"sleepy"})
+	testutil.AssertFalse(t, r.Contains("cute"), "Set should replace the old tags")
+}
+
+// TestTagRegistry_Ready tests that the readiness signal fires once tags arrive
+func TestTagRegistry_Ready(t *testing.T) {
+	r := NewTagRegistry(0, "")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		r.Set(CAASTags{"cute"})
+	}()
+
+	select {
+	case <-r.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ready was never closed")
+	}
+
+	// a second Set must not close the channel again
+	testutil.AssertNoPanic(t, func() { r.Set(CAASTags{"orange"}) }, "second Set should not panic")
+}
+
+// TestTagRegistry_Refresh tests fetching tags and writing the disk cache
+func TestTagRegistry_Refresh(t *testing.T) {
+	server, _ := newTagServer(t, `["cute","orange"]`, http.StatusOK)
+	path := filepath.Join(testutil.CreateTempDir(t), "nested", tagCacheFileName)
+	r := NewTagRegistry(time.Hour, path)
+
+	err := r.Refresh(context.Background(), NewClient().WithBaseURL(server.URL))
+
+	testutil.AssertNoError(t, err, "Refresh should succeed")
+	testutil.AssertTrue(t, r.Contains("cute"), "should contain fetched tag")
+
+	var cached tagCacheFile
+	data, err := os.ReadFile(path)
+	testutil.AssertNoError(t, err, "cache file should exist")
+	testutil.AssertNoError(t, json.Unmarshal(data, &cached), "cache file should be valid JSON")
+	testutil.AssertEqual(t, 2, len(cached.Tags), "cached tags")
+}
+
+// TestTagRegistry_Load tests the cache first loading strategy
+func TestTagRegistry_Load(t *testing.T) {
+	t.Run("fresh_cache_skips_network", func(t *testing.T) {
+		server, calls := newTagServer(t, `["network"]`, http.StatusOK)
+		path := filepath.Join(testutil.CreateTempDir(t), tagCacheFileName)
+		writeTagCache(t, path, time.Now(), CAASTags{"cached"})
+
+		r := NewTagRegistry(time.Hour, path)
+		err := r.Load(context.Background(), NewClient().WithBaseURL(server.URL))
+
+		testutil.AssertNoError(t, err, "Load should succeed")
+		testutil.AssertTrue(t, r.Contains("cached"), "should use cached tags")
+		testutil.AssertEqual(t, int32(0), calls.Load(), "network calls")
+	})
+
+	t.Run("stale_cache_refreshes", func(t *testing.T) {
+		server, calls := newTagServer(t, `["network"]`, http.StatusOK)
+		path := filepath.Join(testutil.CreateTempDir(t), tagCacheFileName)
+		writeTagCache(t, path, time.Now().Add(-48*time.Hour), CAASTags{"cached"})
+
+		r := NewTagRegistry(time.Hour, path)
+		err := r.Load(context.Background(), NewClient().WithBaseURL(server.URL))
+
+		testutil.AssertNoError(t, err, "Load should succeed")
+		testutil.AssertTrue(t, r.Contains("network"), "should use refreshed tags")
+		testutil.AssertEqual(t, int32(1), calls.Load(), "network calls")
+	})
+
+	t.Run("offline_with_cache", func(t *testing.T) {
+		server, _ := newTagServer(t, "", http.StatusServiceUnavailable)
+		path := filepath.Join(testutil.CreateTempDir(t), tagCacheFileName)
+		writeTagCache(t, path, time.Now().Add(-48*time.Hour), CAASTags{"cached"})
+
+		r := NewTagRegistry(time.Hour, path)
+		err := r.Load(context.Background(), NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry))
+
+		testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "refresh error should be returned")
+		testutil.AssertTrue(t, r.IsReady(), "cached tags should still make the registry ready")
+		testutil.AssertTrue(t, r.Contains("cached"), "cached tags should stay usable")
+	})
+
+	t.Run("offline_without_cache", func(t *testing.T) {
+		server, _ := newTagServer(t, "", http.StatusOK)
+		server.Close()
+
+		r := NewTagRegistry(time.Hour, filepath.Join(testutil.CreateTempDir(t), tagCacheFileName))
+		var err error
+		testutil.AssertNoPanic(t, func() {
+			err = r.Load(context.Background(), NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry))
+		}, "Load should not panic when offline")
+
+		testutil.AssertError(t, err, "Load should fail")
+		testutil.AssertFalse(t, r.IsReady(), "registry should not be ready")
+	})
+}
+
+// TestTagRegistry_LoadCache tests disk cache error handling
+// TestTagRegistry_AutoRefresh tests retrying a failed first load and refreshing every TTL
+func TestTagRegistry_AutoRefresh(t *testing.T) {
+	interval := TagRetryInterval
+	TagRetryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { TagRetryInterval = interval })
+
+	server, tagCalls, _ := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+	c := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry)
+	r := NewTagRegistry(50*time.Millisecond, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.AutoRefresh(ctx, c)
+		close(done)
+	}()
+
+	select {
+	case <-r.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("tags should load once the server recovers")
+	}
+	testutil.AssertTrue(t, r.Contains("cute"), "tags from the server")
+	testutil.AssertTrue(t, tagCalls.Load() >= 3, "failed loads should be retried")
+
+	loaded := tagCalls.Load()
+	waitFor(t, func() bool { return tagCalls.Load() > loaded }, "tags should refresh every TTL")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AutoRefresh should stop with its context")
+	}
+}
+
+// TestTagRegistry_LoadCache tests loading tags without a cache path, from a missing file and from a corrupt one
+func TestTagRegistry_LoadCache(t *testing.T) {
+	t.Run("no_cache_path", func(t *testing.T) {
+		err := NewTagRegistry(time.Hour, "").LoadCache()
+		testutil.AssertTrue(t, errors.Is(err, ErrNoTagCache), "should report ErrNoTagCache")
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		err := NewTagRegistry(time.Hour, filepath.Join(testutil.CreateTempDir(t), "missing.json")).LoadCache()
+		testutil.AssertTrue(t, errors.Is(err, os.ErrNotExist), "should report a missing file")
+	})
+
+	t.Run("corrupt_file", func(t *testing.T) {
+		path := testutil.WriteTestFile(t, testutil.CreateTempDir(t), tagCacheFileName, []byte("{not json"))
+		r := NewTagRegistry(time.Hour, path)
+		testutil.AssertError(t, r.LoadCache(), "should fail on corrupt cache")
+		testutil.AssertFalse(t, r.IsReady(), "corrupt cache should not make the registry ready")
+	})
+}
+
+// TestTagRegistry_Count tests the per tag count cache
+func TestTagRegistry_Count(t *testing.T) {
+	server, calls := newCatListServer(t, 25)
+	c := NewClient().WithBaseURL(server.URL)
+
+	t.Run("cached", func(t *testing.T) {
+		calls.Store(0)
+		r := NewTagRegistry(time.Hour, "")
+		r.Set(CAASTags{"orange", "cat"})
+
+		_, fresh := r.CachedCount("orange")
+		testutil.AssertFalse(t, fresh, "nothing cached yet")
+
+		for range 3 {
+			n, err := r.Count(context.Background(), c, "orange")
+			testutil.AssertNoError(t, err, "Count should succeed")
+			testutil.AssertEqual(t, 13, n, "orange cats")
+		}
+		testutil.AssertEqual(t, int32(1), calls.Load(), "one request for repeated counts")
+
+		n, fresh := r.CachedCount("orange")
+		testutil.AssertTrue(t, fresh, "count should be cached")
+		testutil.AssertEqual(t, 13, n, "cached count")
+
+		all, err := r.Count(context.Background(), c, "")
+		testutil.AssertNoError(t, err, "Count should succeed")
+		testutil.AssertEqual(t, 25, all, "all cats")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		calls.Store(0)
+		r := NewTagRegistry(time.Nanosecond, "")
+
+		_, err := r.Count(context.Background(), c, "orange")
+		testutil.AssertNoError(t, err, "Count should succeed")
+		time.Sleep(time.Millisecond)
+		_, fresh := r.CachedCount("orange")
+		testutil.AssertFalse(t, fresh, "count should have expired")
+		_, err = r.Count(context.Background(), c, "orange")
+		testutil.AssertNoError(t, err, "Count should succeed")
+		testutil.AssertEqual(t, int32(2), calls.Load(), "expired count refetched")
+	})
+
+	t.Run("unknown_tag", func(t *testing.T) {
+		calls.Store(0)
+		r := NewTagRegistry(time.Hour, "")
+		r.Set(CAASTags{"orange"})
+
+		_, err := r.Count(context.Background(), c, "dog")
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidTag), "should be ErrInvalidTag")
+		testutil.AssertEqual(t, int32(0), calls.Load(), "no request for an unknown tag")
+	})
+
+	t.Run("removed_tag_forgotten", func(t *testing.T) {
+		r := NewTagRegistry(time.Hour, "")
+		r.Set(CAASTags{"orange"})
+		_, err := r.Count(context.Background(), c, "orange")
+		testutil.AssertNoError(t, err, "Count should succeed")
+
+		r.Set(CAASTags{"cat"})
+		_, ok := r.CachedCount("orange")
+		testutil.AssertFalse(t, ok, "count for a removed tag should be dropped")
+	})
+}
+
+// TestTagRegistry_ConcurrentAccess tests concurrent reads while the tags are replaced
+func TestTagRegistry_ConcurrentAccess(t *testing.T) {
+	r := NewTagRegistry(time.Hour, "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Set(CAASTags{"cute", "orange"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = r.Contains("cute")
+				_ = r.Tags()
+				_ = r.Stale()
+			}
+		}()
+	}
+	wg.Wait()
+
+	testutil.AssertTrue(t, r.Contains("orange"), "final state should be consistent")
+}
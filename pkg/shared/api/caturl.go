@@ -2,21 +2,17 @@ package api
 
 import (
 	"fmt"
+	"maps"
 	"net/url"
 	"slices"
 	"strconv"
 	"strings"
-
-	"github.com/g4s8/hexcolor"
 )
 
 const (
 	caasBaseURL       = "https://cataas.com/cat"
 	caasSaysEndpoint  = "says"
 	caasQueryStart    = "?"
-	caasQueryAnd      = "&"
-	caasReturnJSON    = "json=true"
-	caasReturnHTML    = "html=true"
 	caasPathSeparator = '/'
 )
 
@@ -31,8 +27,10 @@ const (
 	caasKeyHeight   = "height"
 	caasKeyBlur     = "blur"
 
-	//caasKeyJSON = "json"
-	//caasKeyHTML = "html"
+	// Output Params
+
+	caasKeyJSON = "json"
+	caasKeyHTML = "html"
 
 	// Custom Filter Params
 
@@ -75,9 +73,10 @@ type CatURL struct {
 	hasSays      bool // used to determine if using text overlay
 	saysText     string
 	customFilter bool
-	params       []string // store params
+	params       url.Values // store params
 	asJSON       bool
 	asHTML       bool
+	rejected     []ValidationError // options the builder methods refused
 }
 
 /*
@@ -102,751 +101,250 @@ type CatURL struct {
 func NewCatURL() *CatURL {
 	return &CatURL{
 		baseURL: caasBaseURL,
-		params:  make([]string, 0),
+		params:  make(url.Values),
+	}
+}
+
+// clone returns a deep copy so builder methods never share state
+func (c *CatURL) clone() *CatURL {
+	cp := *c
+	cp.params = make(url.Values, len(c.params))
+	for key, values := range c.params {
+		cp.params[key] = slices.Clone(values)
 	}
+	cp.rejected = slices.Clone(c.rejected)
+	return &cp
 }
 
-func (c *CatURL) updateParams(key, value string) []string {
-	param := fmt.Sprintf("%s=%s", key, value)
-	updatedParams := append(c.params, param)
-	return updatedParams
+// reject returns an unchanged copy that remembers why an option was refused,
+// so Generate can report it instead of silently dropping it
+func (c *CatURL) reject(field, value, reason string, err error) *CatURL {
+	cp := c.clone()
+	cp.rejected = append(cp.rejected, ValidationError{Field: field, Value: value, Reason: reason, Err: err})
+	return cp
+}
+
+// withParam returns a copy with key set to value, replacing any earlier value
+func (c *CatURL) withParam(key, value string) *CatURL {
+	cp := c.clone()
+	cp.params.Set(key, value)
+	return cp
 }
 
 func (c *CatURL) WithID(id string) *CatURL {
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        id,
-		hasID:        true,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       c.params,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
-	}
+	cp := c.clone()
+	cp.catID = id
+	cp.hasID = true
+	return cp
 }
 
 func (c *CatURL) WithTag(tag string) *CatURL {
-
-	if !slices.Contains(AvailableTags, tag) {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
+	if tag == "" {
+		return c.reject("tag", tag, "must not be empty", ErrInvalidTag)
 	}
-
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          tag,
-		hasTag:       true,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       c.params,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+	// tags can only be checked once the registry has loaded
+	if AvailableTags.Len() > 0 && !AvailableTags.Contains(tag) {
+		return c.reject("tag", tag, "not a known cataas tag", ErrInvalidTag)
 	}
+	cp := c.clone()
+	cp.tag = tag
+	cp.hasTag = true
+	return cp
 }
 
 func (c *CatURL) WithSays(txt string) *CatURL {
-	cleaned := url.QueryEscape(txt)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		hasID:        c.hasID,
-		catID:        c.catID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      true,
-		saysText:     cleaned,
-		customFilter: c.customFilter,
-		params:       c.params,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
-	}
+	cp := c.clone()
+	cp.hasSays = true
+	cp.saysText = txt
+	return cp
 }
 
 func (c *CatURL) WithCAASImageType(imgType CAASImageType) *CatURL {
 	// Get the str repr if it exists
 	str, exists := CAASImageTypes[imgType]
 	if !exists {
-		// we're just returning the existing data
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-
-	// generate the param
-	updatedParams := c.updateParams(caasKeyType, str)
-
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyType, strconv.Itoa(int(imgType)), "not a supported value", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyType, str)
 }
 
 func (c *CatURL) WithCAASImageFilter(filter CAASImageFilter) *CatURL {
 	str, exists := CAASImageFilters[filter]
 	if !exists {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	isCustom := false
-	if filter == CAASImageFilterCustom {
-		isCustom = true
-	}
-	updatedParams := c.updateParams(caasKeyFilter, str)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: isCustom,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyFilter, strconv.Itoa(int(filter)), "not a supported value", ErrInvalidOption)
 	}
+	cp := c.withParam(caasKeyFilter, str)
+	cp.customFilter = filter == CAASImageFilterCustom
+	return cp
 }
 
 func (c *CatURL) WithCAASImageFit(fit CAASImageFit) *CatURL {
 	str, exists := CAASImageFits[fit]
 	if !exists {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyFit, str)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyFit, strconv.Itoa(int(fit)), "not a supported value", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyFit, str)
 }
 
 func (c *CatURL) WithCAASImagePosition(position CAASImagePosition) *CatURL {
 	str, exists := CAASImagePositions[position]
 	if !exists {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyPosition, str)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyPosition, strconv.Itoa(int(position)), "not a supported value", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyPosition, str)
 }
 
 func (c *CatURL) WithWidth(width int) *CatURL {
-	updatedParams := c.updateParams(caasKeyWidth, strconv.Itoa(width))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
-	}
+	return c.withParam(caasKeyWidth, strconv.Itoa(width))
 }
 
 func (c *CatURL) WithHeight(height int) *CatURL {
-	updatedParams := c.updateParams(caasKeyHeight, strconv.Itoa(height))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
-	}
+	return c.withParam(caasKeyHeight, strconv.Itoa(height))
 }
 
 func (c *CatURL) WithBlur(blur int) *CatURL {
-	updatedParams := c.updateParams(caasKeyBlur, strconv.Itoa(blur))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
-	}
+	return c.withParam(caasKeyBlur, strconv.Itoa(blur))
 }
 
 func (c *CatURL) WithFilterR(r int) *CatURL {
 	if !validRGBValue(r) {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyRed, strconv.Itoa(r))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyRed, strconv.Itoa(r), "must be between 0 and 255", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyRed, strconv.Itoa(r))
 }
 
 func (c *CatURL) WithFilterG(g int) *CatURL {
 	if !validRGBValue(g) {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyGreen, strconv.Itoa(g))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyGreen, strconv.Itoa(g), "must be between 0 and 255", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyGreen, strconv.Itoa(g))
 }
 
 func (c *CatURL) WithFilterB(b int) *CatURL {
 	if !validRGBValue(b) {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyBlue, strconv.Itoa(b))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyBlue, strconv.Itoa(b), "must be between 0 and 255", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyBlue, strconv.Itoa(b))
 }
 
 // WithFilterRGB is a convenience function combining all 3 values
 func (c *CatURL) WithFilterRGB(r, g, b int) *CatURL {
-	if !validRGBValue(r) || !validRGBValue(g) || !validRGBValue(b) {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-
 	if !c.customFilter {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-
-	rParam := fmt.Sprintf("%s=%s", caasKeyRed, strconv.Itoa(r))
-	gParam := fmt.Sprintf("%s=%s", caasKeyGreen, strconv.Itoa(g))
-	bParam := fmt.Sprintf("%s=%s", caasKeyBlue, strconv.Itoa(b))
-
-	updatedParams := append(c.params, rParam, gParam, bParam)
-
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		rgb := fmt.Sprintf("%d,%d,%d", r, g, b)
+		return c.reject("rgb", rgb, "only applies with filter=custom", ErrCustomFilterRequired)
 	}
+	return c.WithFilterR(r).WithFilterG(g).WithFilterB(b)
 }
 
 func (c *CatURL) WithBrightness(brightness int) *CatURL {
 	if !c.customFilter {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyBrightness, strconv.Itoa(brightness))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyBrightness, strconv.Itoa(brightness), "only applies with filter=custom", ErrCustomFilterRequired)
 	}
+	return c.withParam(caasKeyBrightness, strconv.Itoa(brightness))
 }
 
 func (c *CatURL) WithSaturation(saturation int) *CatURL {
 	if !c.customFilter {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeySaturation, strconv.Itoa(saturation))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeySaturation, strconv.Itoa(saturation), "only applies with filter=custom", ErrCustomFilterRequired)
 	}
+	return c.withParam(caasKeySaturation, strconv.Itoa(saturation))
 }
 
 func (c *CatURL) WithHue(hue int) *CatURL {
 	if !c.customFilter {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyHue, strconv.Itoa(hue))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyHue, strconv.Itoa(hue), "only applies with filter=custom", ErrCustomFilterRequired)
 	}
+	return c.withParam(caasKeyHue, strconv.Itoa(hue))
 }
 
 func (c *CatURL) WithLightness(lightness int) *CatURL {
 	if !c.customFilter {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyLightness, strconv.Itoa(lightness))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyLightness, strconv.Itoa(lightness), "only applies with filter=custom", ErrCustomFilterRequired)
 	}
+	return c.withParam(caasKeyLightness, strconv.Itoa(lightness))
 }
 
 func (c *CatURL) WithFont(font CAASFont) *CatURL {
+	str, exists := CAASFonts[font]
+	value := str
+	if !exists {
+		value = strconv.Itoa(int(font))
+	}
 	if !c.hasSays {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
+		return c.reject(caasKeyFont, value, "only applies to a Says URL", ErrFontNoSays)
 	}
-	str, exists := CAASFonts[font]
 	if !exists {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyFont, str)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyFont, value, "not a supported value", ErrInvalidOption)
 	}
+	return c.withParam(caasKeyFont, str)
 }
 
 func (c *CatURL) WithFontSize(size int) *CatURL {
 	if !c.hasSays {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyFontSize, strconv.Itoa(size))
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		hasID:        c.hasID,
-		tag:          c.tag,
-		hasTag:       c.hasTag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyFontSize, strconv.Itoa(size), "only applies to a Says URL", ErrFontNoSays)
 	}
+	return c.withParam(caasKeyFontSize, strconv.Itoa(size))
 }
 
 func (c *CatURL) WithFontColor(hexColor string) *CatURL {
 	if !c.hasSays {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-
-	_, err := hexcolor.Parse(hexColor)
-	if err != nil {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			hasID:        c.hasID,
-			tag:          c.tag,
-			hasTag:       c.hasTag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-
-	updatedParams := c.updateParams(caasKeyFontColor, hexColor)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		tag:          c.tag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyFontColor, hexColor, "only applies to a Says URL", ErrFontNoSays)
+	}
+	if !validHexColor(hexColor) {
+		return c.reject(caasKeyFontColor, hexColor, "must be a hex color like #ff00ff", ErrInvalidColor)
 	}
+	return c.withParam(caasKeyFontColor, hexColor)
 }
 
 func (c *CatURL) WithFontBackground(hexColor string) *CatURL {
 	if !c.hasSays {
-		return &CatURL{
-			baseURL:      c.baseURL,
-			catID:        c.catID,
-			tag:          c.tag,
-			hasSays:      c.hasSays,
-			saysText:     c.saysText,
-			customFilter: c.customFilter,
-			params:       c.params,
-			asJSON:       c.asJSON,
-			asHTML:       c.asHTML,
-		}
-	}
-	updatedParams := c.updateParams(caasKeyFontBackground, hexColor)
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		tag:          c.tag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       updatedParams,
-		asJSON:       c.asJSON,
-		asHTML:       c.asHTML,
+		return c.reject(caasKeyFontBackground, hexColor, "only applies to a Says URL", ErrFontNoSays)
+	}
+	if !validHexColor(hexColor) {
+		return c.reject(caasKeyFontBackground, hexColor, "must be a hex color like #ff00ff", ErrInvalidColor)
 	}
+	return c.withParam(caasKeyFontBackground, hexColor)
 }
 
 func (c *CatURL) AsJSON() *CatURL {
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		tag:          c.tag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       c.params,
-		asJSON:       true,
-		asHTML:       c.asHTML,
-	}
+	cp := c.clone()
+	cp.asJSON = true
+	return cp
 }
 
 func (c *CatURL) AsHTML() *CatURL {
-	return &CatURL{
-		baseURL:      c.baseURL,
-		catID:        c.catID,
-		tag:          c.tag,
-		hasSays:      c.hasSays,
-		saysText:     c.saysText,
-		customFilter: c.customFilter,
-		params:       c.params,
-		asJSON:       c.asJSON,
-		asHTML:       true,
-	}
+	cp := c.clone()
+	cp.asHTML = true
+	return cp
 }
 
-func (c *CatURL) Generate() (string, error) {
-
-	// Bad Combos fail fast
-	if c.hasID && c.hasTag {
-		return "", ErrIDAndTag
+// query returns every query parameter, including the output format
+func (c *CatURL) query() url.Values {
+	query := maps.Clone(c.params)
+	if query == nil {
+		query = make(url.Values)
 	}
-	if c.hasSays && c.saysText == "" {
-		return "", ErrSaysNoText
+	if c.asJSON {
+		query.Set(caasKeyJSON, "true")
 	}
-	if c.hasTag && slices.Contains(AvailableTags, c.tag) {
-		return "", ErrInvalidTag
+	if c.asHTML {
+		query.Set(caasKeyHTML, "true")
 	}
-	if c.asHTML && c.asJSON {
-		return "", ErrHTMLAndJSON
+	return query
+}
+
+// Err returns every option rejected by the builder methods and every problem
+// found by Validate as one joined error, or nil when Generate will succeed.
+func (c *CatURL) Err() error {
+	return joinProblems(c.Validate())
+}
+
+// Generate validates the CatURL and builds the URL. Every problem found by
+// Validate is reported in the returned error, which matches the individual
+// sentinel errors with errors.Is.
+func (c *CatURL) Generate() (string, error) {
+	if err := c.Err(); err != nil {
+		return "", err
 	}
 
 	// write the base
@@ -856,40 +354,25 @@ func (c *CatURL) Generate() (string, error) {
 	// add the ID/Tag if present
 	if c.hasID {
 		b.WriteRune(caasPathSeparator)
-		b.WriteString(c.catID)
+		b.WriteString(url.PathEscape(c.catID))
 	}
 	if c.hasTag {
 		b.WriteRune(caasPathSeparator)
-		b.WriteString(c.tag)
+		b.WriteString(url.PathEscape(c.tag))
 	}
 	// add text overlay if present
 	if c.hasSays {
 		b.WriteRune(caasPathSeparator)
 		b.WriteString(caasSaysEndpoint)
 		b.WriteRune(caasPathSeparator)
-		b.WriteString(c.saysText)
+		b.WriteString(url.PathEscape(c.saysText))
 	}
 
-	hasParams := len(c.params) > 0
-
-	// if there are query params, process them
-	if hasParams {
+	// Encode sorts by key so the same options always produce the same URL
+	if query := c.query(); len(query) > 0 {
 		b.WriteString(caasQueryStart)
-		query := strings.Join(c.params, caasQueryAnd)
-		b.WriteString(query)
-	} else if c.asJSON || c.asHTML {
-		b.WriteString(caasQueryStart)
-	}
-	// add output param if present
-	if c.asJSON {
-		b.WriteString(caasQueryAnd)
-		b.WriteString(caasReturnJSON)
-	}
-	if c.asHTML {
-		b.WriteString(caasQueryAnd)
-		b.WriteString(caasReturnHTML)
+		b.WriteString(query.Encode())
 	}
 
 	return b.String(), nil
-
 }
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotCatURL     = fmt.Errorf("not a cataas cat url")
+	ErrUnknownOption = fmt.Errorf("unknown query parameter")
+)
+
+const caasCatSegment = "cat"
+
+// lookup is the reverse of the CAAS* maps in types.go
+func lookup[K comparable](m map[K]string, value string) (K, bool) {
+	for k, v := range m {
+		if v == value {
+			return k, true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// ParseCatURL turns a cataas link back into a CatURL. It understands every
+// path and query parameter the builder emits. A single segment after /cat is
+// read as a tag when AvailableTags knows it and as a cat id otherwise.
+func ParseCatURL(raw string) (*CatURL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q has no scheme or host", ErrNotCatURL, raw)
+	}
+
+	// split the escaped path so an escaped slash in the text stays in one segment
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	catIndex := -1
+	for i, segment := range segments {
+		if segment == caasCatSegment {
+			catIndex = i
+			break
+		}
+	}
+	if catIndex < 0 {
+		return nil, fmt.Errorf("%w: %q has no /cat path", ErrNotCatURL, raw)
+	}
+
+	c := NewCatURL()
+	c.baseURL = u.Scheme + "://" + u.Host + "/" + strings.Join(segments[:catIndex+1], "/")
+
+	if err := c.parsePath(segments[catIndex+1:]); err != nil {
+		return nil, err
+	}
+	if err := c.parseQuery(u.Query()); err != nil {
+		return nil, err
+	}
+
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parsePath handles everything after /cat: [{id|tag}][/says/{text}]
+func (c *CatURL) parsePath(segments []string) error {
+	unescaped := make([]string, len(segments))
+	for i, segment := range segments {
+		s, err := url.PathUnescape(segment)
+		if err != nil {
+			return err
+		}
+		unescaped[i] = s
+	}
+
+	switch {
+	case len(unescaped) == 0 || (len(unescaped) == 1 && unescaped[0] == ""):
+		return nil
+	case len(unescaped) == 1:
+		c.setIDOrTag(unescaped[0])
+		return nil
+	case len(unescaped) == 2 && unescaped[0] == caasSaysEndpoint:
+		c.hasSays = true
+		c.saysText = unescaped[1]
+		return nil
+	case len(unescaped) == 3 && unescaped[1] == caasSaysEndpoint:
+		c.setIDOrTag(unescaped[0])
+		c.hasSays = true
+		c.saysText = unescaped[2]
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported path /%s/%s", ErrNotCatURL, caasCatSegment, strings.Join(segments, "/"))
+}
+
+func (c *CatURL) setIDOrTag(segment string) {
+	if AvailableTags.Contains(segment) {
+		c.tag = segment
+		c.hasTag = true
+		return
+	}
+	c.catID = segment
+	c.hasID = true
+}
+
+func (c *CatURL) parseQuery(query url.Values) error {
+	var errs []error
+	enum := func(key string, known bool) {
+		if !known {
+			errs = append(errs, ValidationError{Field: key, Value: query.Get(key), Reason: "not a supported value", Err: ErrInvalidOption})
+		}
+	}
+
+	// sorted so the same URL always reports its problems in the same order
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		value := query.Get(key)
+		switch key {
+		case caasKeyJSON:
+			c.asJSON = value == "true"
+		case caasKeyHTML:
+			c.asHTML = value == "true"
+		case caasKeyType:
+			_, ok := lookup(CAASImageTypes, value)
+			enum(key, ok)
+		case caasKeyFilter:
+			filter, ok := lookup(CAASImageFilters, value)
+			enum(key, ok)
+			c.customFilter = ok && filter == CAASImageFilterCustom
+		case caasKeyFit:
+			_, ok := lookup(CAASImageFits, value)
+			enum(key, ok)
+		case caasKeyPosition:
+			_, ok := lookup(CAASImagePositions, value)
+			enum(key, ok)
+		case caasKeyFont:
+			_, ok := lookup(CAASFonts, value)
+			enum(key, ok)
+		case caasKeyWidth, caasKeyHeight, caasKeyBlur, caasKeyFontSize,
+			caasKeyRed, caasKeyGreen, caasKeyBlue,
+			caasKeyBrightness, caasKeySaturation, caasKeyHue, caasKeyLightness:
+			if _, err := strconv.Atoi(value); err != nil {
+				errs = append(errs, ValidationError{Field: key, Value: value, Reason: "must be an integer", Err: ErrInvalidOption})
+			}
+		case caasKeyFontColor, caasKeyFontBackground:
+			// checked by Validate
+		default:
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownOption, key))
+			continue
+		}
+		if key != caasKeyJSON && key != caasKeyHTML {
+			c.params.Set(key, value)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// TestParseCatURL tests that generated URLs parse back to the same options
+func TestParseCatURL(t *testing.T) {
+	withTags(t, "orange", "cute")
+
+	tests := []struct {
+		name   string
+		catURL *CatURL
+	}{
+		{"base", NewCatURL()},
+		{"id", NewCatURL().WithID("abc123")},
+		{"tag", NewCatURL().WithTag("orange")},
+		{"says", NewCatURL().WithSays("Hello World!")},
+		{"id_says", NewCatURL().WithID("abc123").WithSays("a/b c")},
+		{"tag_says", NewCatURL().WithTag("cute").WithSays("hi")},
+		{"basic_params", NewCatURL().
+			WithCAASImageType(CAASImageTypeSquare).
+			WithCAASImageFit(CAASImageFitContain).
+			WithCAASImagePosition(CAASImagePositionRightTop).
+			WithWidth(300).WithHeight(200).WithBlur(2)},
+		{"custom_filter", NewCatURL().
+			WithCAASImageFilter(CAASImageFilterCustom).
+			WithFilterRGB(10, 20, 30).
+			WithBrightness(5).WithSaturation(6).WithHue(-7).WithLightness(8)},
+		{"font", NewCatURL().WithSays("hi").
+			WithFont(CAASFontComicSansMS).WithFontSize(40).
+			WithFontColor("#ff00ff").WithFontBackground("#000")},
+		{"json", NewCatURL().WithID("abc").AsJSON()},
+		{"html", NewCatURL().AsHTML()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := tt.catURL.Generate()
+			testutil.AssertNoError(t, err, "Generate")
+
+			parsed, err := ParseCatURL(expected)
+			testutil.AssertNoError(t, err, "ParseCatURL")
+
+			got, err := parsed.Generate()
+			testutil.AssertNoError(t, err, "Generate after parse")
+			testutil.AssertEqual(t, expected, got, "round trip")
+		})
+	}
+}
+
+// TestParseCatURL_Fields tests that parsed values land in the right fields
+func TestParseCatURL_Fields(t *testing.T) {
+	withTags(t, "orange")
+
+	t.Run("tag_or_id", func(t *testing.T) {
+		tagged, err := ParseCatURL("https://cataas.com/cat/orange")
+		testutil.AssertNoError(t, err, "parse tag")
+		testutil.AssertTrue(t, tagged.hasTag && tagged.tag == "orange", "known tag should parse as a tag")
+
+		byID, err := ParseCatURL("https://cataas.com/cat/xYz123")
+		testutil.AssertNoError(t, err, "parse id")
+		testutil.AssertTrue(t, byID.hasID && byID.catID == "xYz123", "unknown segment should parse as an id")
+	})
+
+	t.Run("says_escaped", func(t *testing.T) {
+		u, err := ParseCatURL("https://cataas.com/cat/says/100%25%20a%2Fb?fontSize=20")
+		testutil.AssertNoError(t, err, "parse says")
+		testutil.AssertEqual(t, "100% a/b", u.saysText, "says text")
+		testutil.AssertEqual(t, "20", u.params.Get(caasKeyFontSize), "fontSize")
+	})
+
+	t.Run("custom_base", func(t *testing.T) {
+		u, err := ParseCatURL("http://localhost:8080/cat/abc?json=true")
+		testutil.AssertNoError(t, err, "parse custom base")
+		testutil.AssertEqual(t, "http://localhost:8080/cat", u.baseURL, "base url")
+		testutil.AssertTrue(t, u.asJSON, "json should be set")
+	})
+
+	t.Run("custom_filter", func(t *testing.T) {
+		u, err := ParseCatURL("https://cataas.com/cat?filter=custom&r=1")
+		testutil.AssertNoError(t, err, "parse custom filter")
+		testutil.AssertTrue(t, u.customFilter, "custom filter should be set")
+	})
+}
+
+// TestParseCatURL_Errors tests rejection of URLs the builder cannot produce
+func TestParseCatURL_Errors(t *testing.T) {
+	withTags(t, "orange")
+
+	tests := []struct {
+		name     string
+		raw      string
+		expected error
+	}{
+		{"relative", "/cat/abc", ErrNotCatURL},
+		{"no_cat", "https://cataas.com/dog", ErrNotCatURL},
+		{"too_deep", "https://cataas.com/cat/a/b/c/d", ErrNotCatURL},
+		{"unknown_param", "https://cataas.com/cat?size=big", ErrUnknownOption},
+		{"bad_enum", "https://cataas.com/cat?filter=sepia", ErrInvalidOption},
+		{"bad_number", "https://cataas.com/cat?width=wide", ErrInvalidOption},
+		{"bad_color", "https://cataas.com/cat/says/hi?fontColor=red", ErrInvalidColor},
+		{"empty_color", "https://cataas.com/cat/says/hi?fontColor=", ErrInvalidColor},
+		{"empty_background", "https://cataas.com/cat/says/hi?fontBackground=", ErrInvalidColor},
+		{"font_without_says", "https://cataas.com/cat?font=Impact", ErrFontNoSays},
+		{"rgb_without_custom", "https://cataas.com/cat?r=10", ErrCustomFilterRequired},
+		{"empty_says", "https://cataas.com/cat/abc/says/", ErrNotCatURL},
+		{"html_and_json", "https://cataas.com/cat?html=true&json=true", ErrHTMLAndJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := ParseCatURL(tt.raw)
+			testutil.AssertError(t, err, "ParseCatURL should fail")
+			testutil.AssertNil(t, u, "no CatURL on error")
+			testutil.AssertTrue(t, errors.Is(err, tt.expected), "expected "+tt.expected.Error()+", got "+err.Error())
+		})
+	}
+}
+
+// TestParseCatURL_ErrorOrder tests that the problems with a URL are always
+// reported in the same order, sorted by parameter
+func TestParseCatURL_ErrorOrder(t *testing.T) {
+	raw := "https://cataas.com/cat?width=wide&size=big&filter=sepia&height=tall"
+	expected := "filter=\"sepia\": not a supported value\n" +
+		"height=\"tall\": must be an integer\n" +
+		"unknown query parameter: size\n" +
+		"width=\"wide\": must be an integer"
+
+	for range 20 {
+		_, err := ParseCatURL(raw)
+		testutil.AssertError(t, err, "ParseCatURL should fail")
+		testutil.AssertEqual(t, expected, err.Error(), "error message")
+	}
+}
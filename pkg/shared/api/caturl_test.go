@@ -0,0 +1,309 @@
+package api
+
+import (
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// withTags swaps AvailableTags for a registry holding tags for the duration of the test
+func withTags(t *testing.T, tags ...string) {
+	t.Helper()
+	old := AvailableTags
+	AvailableTags = NewTagRegistry(0, "")
+	if len(tags) > 0 {
+		AvailableTags.Set(tags)
+	}
+	t.Cleanup(func() { AvailableTags = old })
+}
+
+// TestCatURL_Generate tests URL generation for the supported endpoint shapes
+func TestCatURL_Generate(t *testing.T) {
+	withTags(t, "orange", "cute")
+
+	tests := []struct {
+		name     string
+		catURL   *CatURL
+		expected string
+	}{
+		{
+			name:     "base",
+			catURL:   NewCatURL(),
+			expected: "https://cataas.com/cat",
+		},
+		{
+			name:     "id",
+			catURL:   NewCatURL().WithID("abc123"),
+			expected: "https://cataas.com/cat/abc123",
+		},
+		{
+			name:     "known_tag",
+			catURL:   NewCatURL().WithTag("orange"),
+			expected: "https://cataas.com/cat/orange",
+		},
+		{
+			name:     "json_only",
+			catURL:   NewCatURL().AsJSON(),
+			expected: "https://cataas.com/cat?json=true",
+		},
+		{
+			name:     "json_keeps_id",
+			catURL:   NewCatURL().WithID("abc123").AsJSON(),
+			expected: "https://cataas.com/cat/abc123?json=true",
+		},
+		{
+			name:     "html_keeps_tag",
+			catURL:   NewCatURL().WithTag("cute").AsHTML(),
+			expected: "https://cataas.com/cat/cute?html=true",
+		},
+		{
+			name:     "says_is_path_escaped",
+			catURL:   NewCatURL().WithSays("hello world/again?"),
+			expected: "https://cataas.com/cat/says/hello%20world%2Fagain%3F",
+		},
+		{
+			name:     "params_sorted_and_escaped",
+			catURL:   NewCatURL().WithWidth(300).WithCAASImagePosition(CAASImagePositionRightTop).WithCAASImageFit(CAASImageFitCover).AsJSON(),
+			expected: "https://cataas.com/cat?fit=cover&json=true&position=right+top&width=300",
+		},
+		{
+			name:     "font_options",
+			catURL:   NewCatURL().WithSays("hi").WithFont(CAASFontComicSansMS).WithFontColor("#ff0000").WithFontSize(40),
+			expected: "https://cataas.com/cat/says/hi?font=Comic+Sans+MS&fontColor=%23ff0000&fontSize=40",
+		},
+		{
+			name:     "custom_filter",
+			catURL:   NewCatURL().WithCAASImageFilter(CAASImageFilterCustom).WithFilterRGB(10, 20, 30).WithHue(90),
+			expected: "https://cataas.com/cat?b=30&filter=custom&g=20&hue=90&r=10",
+		},
+		{
+			name:     "repeated_option_replaces",
+			catURL:   NewCatURL().WithWidth(100).WithWidth(200),
+			expected: "https://cataas.com/cat?width=200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.catURL.Generate()
+			testutil.AssertNoError(t, err, "Generate should succeed")
+			testutil.AssertEqual(t, tt.expected, got, "generated URL")
+		})
+	}
+}
+
+// TestCatURL_Generate_Deterministic tests that option order doesn't change the URL
+func TestCatURL_Generate_Deterministic(t *testing.T) {
+	a, errA := NewCatURL().WithBlur(2).WithCAASImageType(CAASImageTypeSmall).WithHeight(50).Generate()
+	b, errB := NewCatURL().WithHeight(50).WithBlur(2).WithCAASImageType(CAASImageTypeSmall).Generate()
+
+	testutil.AssertNoError(t, errA, "first Generate")
+	testutil.AssertNoError(t, errB, "second Generate")
+	testutil.AssertEqual(t, a, b, "same options should give the same URL")
+}
+
+// TestCatURL_Immutable tests that builder methods never change the receiver
+func TestCatURL_Immutable(t *testing.T) {
+	base := NewCatURL().WithWidth(100)
+	_ = base.WithHeight(50)
+	_ = base.WithWidth(999)
+	_ = base.AsJSON()
+
+	got, err := base.Generate()
+	testutil.AssertNoError(t, err, "Generate should succeed")
+	testutil.AssertEqual(t, "https://cataas.com/cat?width=100", got, "base should be unchanged")
+
+	// two branches from the same parent must not share params
+	left := base.WithBlur(1)
+	right := base.WithBlur(9)
+	l, _ := left.Generate()
+	r, _ := right.Generate()
+	testutil.AssertEqual(t, "https://cataas.com/cat?blur=1&width=100", l, "left branch")
+	testutil.AssertEqual(t, "https://cataas.com/cat?blur=9&width=100", r, "right branch")
+}
+
+// TestCatURL_Tags tests tag handling against the registry
+func TestCatURL_Tags(t *testing.T) {
+	t.Run("unknown_tag_rejected", func(t *testing.T) {
+		withTags(t, "orange")
+		got, err := NewCatURL().WithTag("dog").Generate()
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidTag), "should report ErrInvalidTag")
+		testutil.AssertEqual(t, "", got, "no URL on error")
+	})
+
+	t.Run("unknown_tag_reported", func(t *testing.T) {
+		withTags(t, "orange")
+		u := NewCatURL()
+		u.tag, u.hasTag = "dog", true
+		_, err := u.Generate()
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidTag), "should report ErrInvalidTag")
+	})
+
+	t.Run("registry_not_loaded", func(t *testing.T) {
+		withTags(t)
+		u := NewCatURL()
+		u.tag, u.hasTag = "anything", true
+		got, err := u.Generate()
+		testutil.AssertNoError(t, err, "tags can't be checked before the registry loads")
+		testutil.AssertEqual(t, "https://cataas.com/cat/anything", got, "tag kept")
+	})
+}
+
+// TestCatURL_Rejected tests that builder methods report the options they refuse
+func TestCatURL_Rejected(t *testing.T) {
+	withTags(t, "orange")
+
+	tests := []struct {
+		name     string
+		catURL   *CatURL
+		field    string
+		expected error
+	}{
+		{"empty_tag", NewCatURL().WithTag(""), "tag", ErrInvalidTag},
+		{"unknown_type", NewCatURL().WithCAASImageType(CAASImageType(99)), caasKeyType, ErrInvalidOption},
+		{"unknown_filter", NewCatURL().WithCAASImageFilter(CAASImageFilter(99)), caasKeyFilter, ErrInvalidOption},
+		{"unknown_fit", NewCatURL().WithCAASImageFit(CAASImageFit(99)), caasKeyFit, ErrInvalidOption},
+		{"unknown_position", NewCatURL().WithCAASImagePosition(CAASImagePosition(99)), caasKeyPosition, ErrInvalidOption},
+		{"red_out_of_range", NewCatURL().WithCAASImageFilter(CAASImageFilterCustom).WithFilterR(256), caasKeyRed, ErrInvalidOption},
+		{"green_out_of_range", NewCatURL().WithCAASImageFilter(CAASImageFilterCustom).WithFilterG(-1), caasKeyGreen, ErrInvalidOption},
+		{"rgb_without_custom", NewCatURL().WithFilterRGB(1, 2, 3), "rgb", ErrCustomFilterRequired},
+		{"hue_without_custom", NewCatURL().WithHue(10), caasKeyHue, ErrCustomFilterRequired},
+		{"font_without_says", NewCatURL().WithFont(CAASFontImpact), caasKeyFont, ErrFontNoSays},
+		{"unknown_font", NewCatURL().WithSays("hi").WithFont(CAASFont(99)), caasKeyFont, ErrInvalidOption},
+		{"font_size_without_says", NewCatURL().WithFontSize(20), caasKeyFontSize, ErrFontNoSays},
+		{"bad_font_color", NewCatURL().WithSays("hi").WithFontColor("red"), caasKeyFontColor, ErrInvalidColor},
+		{"empty_font_color", NewCatURL().WithSays("hi").WithFontColor(""), caasKeyFontColor, ErrInvalidColor},
+		{"background_without_says", NewCatURL().WithFontBackground("#fff"), caasKeyFontBackground, ErrFontNoSays},
+		{"empty_font_background", NewCatURL().WithSays("hi").WithFontBackground(""), caasKeyFontBackground, ErrInvalidColor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.catURL.Validate()
+			testutil.AssertEqual(t, 1, len(problems), "problem count")
+			testutil.AssertEqual(t, tt.field, problems[0].Field, "field")
+
+			_, err := tt.catURL.Generate()
+			testutil.AssertTrue(t, errors.Is(err, tt.expected), "expected "+tt.expected.Error())
+		})
+	}
+
+	t.Run("rejected_font_value", func(t *testing.T) {
+		for _, u := range []*CatURL{
+			NewCatURL().WithFont(CAASFont(99)),
+			NewCatURL().WithSays("hi").WithFont(CAASFont(99)),
+		} {
+			problems := u.Validate()
+			testutil.AssertEqual(t, 1, len(problems), "problem count")
+			testutil.AssertEqual(t, "99", problems[0].Value, "the unknown font is reported")
+		}
+		problems := NewCatURL().WithFont(CAASFontImpact).Validate()
+		testutil.AssertEqual(t, CAASFonts[CAASFontImpact], problems[0].Value, "a known font is reported by name")
+	})
+
+	t.Run("every_rejection_reported", func(t *testing.T) {
+		u := NewCatURL().WithTag("dog").WithFontSize(20).WithHue(5).WithWidth(100)
+		err := u.Err()
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidTag), "should report the tag")
+		testutil.AssertTrue(t, errors.Is(err, ErrFontNoSays), "should report the font size")
+		testutil.AssertTrue(t, errors.Is(err, ErrCustomFilterRequired), "should report the hue")
+		testutil.AssertEqual(t, 3, len(u.Validate()), "problem count")
+	})
+
+	t.Run("chaining_continues", func(t *testing.T) {
+		u := NewCatURL().WithFilterR(300).WithWidth(100)
+		testutil.AssertEqual(t, "100", u.params.Get(caasKeyWidth), "later options still apply")
+		testutil.AssertFalse(t, u.params.Has(caasKeyRed), "rejected option is not added")
+	})
+
+	t.Run("parent_unchanged", func(t *testing.T) {
+		base := NewCatURL()
+		_ = base.WithTag("dog")
+		testutil.AssertNoError(t, base.Err(), "rejections must not leak into the parent")
+	})
+}
+
+// TestCatURL_Validate tests that every problem is reported at once
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+		ok   bool
+	}{
+		{"#ff00ff", color.NRGBA{R: 255, B: 255, A: 255}, true},
+		{"#FFF", color.NRGBA{R: 255, G: 255, B: 255, A: 255}, true},
+		{"", color.NRGBA{}, false},
+		{"#", color.NRGBA{}, false},
+		{"ff00ff", color.NRGBA{}, false},
+		{"#ff00f", color.NRGBA{}, false},
+		{"#gg0000", color.NRGBA{}, false},
+		{"red", color.NRGBA{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseHexColor(tt.in)
+			if !tt.ok {
+				testutil.AssertTrue(t, errors.Is(err, ErrInvalidColor), "should be ErrInvalidColor")
+				return
+			}
+			testutil.AssertNoError(t, err, "ParseHexColor")
+			testutil.AssertEqual(t, tt.want, got, "colour")
+		})
+	}
+}
+
+// TestCatURL_Validate tests that Validate reports every problem with a CatURL
+func TestCatURL_Validate(t *testing.T) {
+	withTags(t, "orange")
+
+	t.Run("valid", func(t *testing.T) {
+		problems := NewCatURL().WithTag("orange").WithWidth(10).Validate()
+		testutil.AssertEqual(t, 0, len(problems), "no problems expected")
+	})
+
+	t.Run("many_problems", func(t *testing.T) {
+		u := NewCatURL().WithID("abc").WithTag("orange").WithSays("").AsJSON().AsHTML().WithWidth(-5)
+		u.params.Set(caasKeyRed, "300")
+		u.params.Set(caasKeyFontColor, "red")
+
+		problems := u.Validate()
+
+		fields := make([]string, len(problems))
+		for i, p := range problems {
+			fields[i] = p.Field
+		}
+		testutil.AssertEqual(t, 7, len(problems), "problem count: "+strings.Join(fields, ","))
+
+		expected := []error{ErrIDAndTag, ErrSaysNoText, ErrHTMLAndJSON, ErrInvalidOption, ErrInvalidColor, ErrCustomFilterRequired}
+		_, err := u.Generate()
+		for _, e := range expected {
+			testutil.AssertTrue(t, errors.Is(err, e), "Generate error should match "+e.Error())
+		}
+	})
+
+	t.Run("problem_details", func(t *testing.T) {
+		problems := NewCatURL().WithHeight(0).Validate()
+		testutil.AssertEqual(t, 1, len(problems), "problem count")
+		testutil.AssertEqual(t, "height", problems[0].Field, "field")
+		testutil.AssertEqual(t, "0", problems[0].Value, "value")
+		testutil.AssertEqual(t, "must be a positive integer", problems[0].Reason, "reason")
+		testutil.AssertEqual(t, `height="0": must be a positive integer`, problems[0].Error(), "message")
+	})
+
+	t.Run("font_without_says", func(t *testing.T) {
+		u := NewCatURL()
+		u.params.Set(caasKeyFont, "Impact")
+		problems := u.Validate()
+		testutil.AssertEqual(t, 1, len(problems), "problem count")
+		testutil.AssertTrue(t, errors.Is(problems[0], ErrFontNoSays), "should be ErrFontNoSays")
+	})
+
+	t.Run("empty_id", func(t *testing.T) {
+		_, err := NewCatURL().WithID("").Generate()
+		testutil.AssertTrue(t, errors.Is(err, ErrEmptyID), "should be ErrEmptyID")
+	})
+}
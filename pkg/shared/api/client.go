@@ -0,0 +1,390 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	caasHost         = "https://cataas.com"
+	caasCatPath      = "/cat"
+	caasTagsPath     = "/api/tags"
+	defaultUserAgent = "catfetch"
+)
+
+// Client talks to a cataas compatible server. The zero value is not usable,
+// create one with NewClient and adjust it with the With* methods, each of
+// which returns a modified copy and leaves the receiver untouched.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	retry      RetryPolicy
+	limits     DecodeLimits
+	onWarning  func(meta *CatMetadata, w DecodeWarning)
+	progress   ProgressFunc
+	cache      *DiskCache
+	images     *ImageCache
+	offline    bool
+}
+
+// NewClient returns a Client pointed at cataas.com. The underlying
+// http.Client has no Transport set, so http.DefaultTransport is used.
+func NewClient() *Client {
+	return &Client{
+		baseURL:    caasHost,
+		httpClient: &http.Client{},
+		userAgent:  defaultUserAgent,
+		retry:      DefaultRetryPolicy(),
+		limits:     DefaultDecodeLimits(),
+		onWarning:  logDecodeWarning,
+	}
+}
+
+func (c *Client) copy() *Client {
+	cp := *c
+	return &cp
+}
+
+// WithBaseURL points the client at another server, e.g. an httptest.Server.
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	cp := c.copy()
+	cp.baseURL = strings.TrimRight(baseURL, "/")
+	return cp
+}
+
+// WithHTTPClient replaces the http.Client used for every request.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	if hc == nil {
+		hc = &http.Client{}
+	}
+	cp := c.copy()
+	cp.httpClient = hc
+	return cp
+}
+
+// WithTransport swaps the RoundTripper while keeping the other http.Client settings.
+func (c *Client) WithTransport(rt http.RoundTripper) *Client {
+	hc := *c.httpClient
+	hc.Transport = rt
+	cp := c.copy()
+	cp.httpClient = &hc
+	return cp
+}
+
+// WithTimeout sets the per request timeout of the underlying http.Client.
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	hc := *c.httpClient
+	hc.Timeout = timeout
+	cp := c.copy()
+	cp.httpClient = &hc
+	return cp
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func (c *Client) WithUserAgent(userAgent string) *Client {
+	cp := c.copy()
+	cp.userAgent = userAgent
+	return cp
+}
+
+// WithRetryPolicy sets how failed metadata, image and tag requests are retried.
+func (c *Client) WithRetryPolicy(p RetryPolicy) *Client {
+	cp := c.copy()
+	cp.retry = p
+	return cp
+}
+
+// WithDecodeLimits sets the size limits for decoding fetched images.
+func (c *Client) WithDecodeLimits(l DecodeLimits) *Client {
+	cp := c.copy()
+	cp.limits = l
+	return cp
+}
+
+// WithDecodeWarningHandler replaces logging as the way images that don't
+// match their declared MIME type are reported. A nil fn ignores them.
+func (c *Client) WithDecodeWarningHandler(fn func(meta *CatMetadata, w DecodeWarning)) *Client {
+	cp := c.copy()
+	cp.onWarning = fn
+	return cp
+}
+
+// WithProgress reports the progress of every image download to fn, e.g. to
+// drive a progress bar. A nil fn turns reporting off.
+func (c *Client) WithProgress(fn ProgressFunc) *Client {
+	cp := c.copy()
+	cp.progress = fn
+	return cp
+}
+
+// WithDiskCache keeps downloaded images in d, revalidating them with the
+// server instead of downloading them again. A nil d turns caching off.
+func (c *Client) WithDiskCache(d *DiskCache) *Client {
+	cp := c.copy()
+	cp.cache = d
+	return cp
+}
+
+// WithImageCache keeps decoded images in ic. A cat already in it is
+// returned without downloading or decoding it again. A nil ic turns it off.
+func (c *Client) WithImageCache(ic *ImageCache) *Client {
+	cp := c.copy()
+	cp.images = ic
+	return cp
+}
+
+// WithOffline makes Fetch serve only cats from the disk cache, never going
+// to the network. Requests with nothing cached fail with ErrNotCached.
+func (c *Client) WithOffline(offline bool) *Client {
+	cp := c.copy()
+	cp.offline = offline
+	return cp
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) UserAgent() string {
+	return c.userAgent
+}
+
+// RandomCat fetches a random cat rendered with the options in u. A nil u
+// fetches a plain random cat.
+func (c *Client) RandomCat(ctx context.Context, u *CatURL) (image.Image, *CatMetadata, error) {
+	if u == nil {
+		u = NewCatURL()
+	}
+	return c.Fetch(ctx, u)
+}
+
+// CatByID fetches the cat with the given CatMetadata.ID. An id the server
+// doesn't know returns an error matching ErrCatNotFound.
+func (c *Client) CatByID(ctx context.Context, id string) (image.Image, *CatMetadata, error) {
+	return c.Fetch(ctx, NewCatURL().WithID(id))
+}
+
+// Tags fetches every tag known to the server.
+func (c *Client) Tags(ctx context.Context) (CAASTags, error) {
+	var tags CAASTags
+	if err := c.getJSON(ctx, c.baseURL+caasTagsPath, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// rebase returns a copy of u that targets the client's server
+func (c *Client) rebase(u *CatURL) *CatURL {
+	cp := u.clone()
+	cp.baseURL = c.baseURL + caasCatPath
+	return cp
+}
+
+// ResolveURL turns a possibly relative URL returned by the server, such as
+// CatMetadata.URL, into an absolute one on the client's base URL
+func (c *Client) ResolveURL(ref string) (string, error) {
+	return c.resolve(ref)
+}
+
+// resolve turns a possibly relative URL returned by the server into an absolute one
+func (c *Client) resolve(ref string) (string, error) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(r).String(), nil
+}
+
+// imageURL picks the URL the rendered image is downloaded from. When u
+// carries no rendering options the URL reported in the metadata is used,
+// otherwise the request is rebuilt for the exact cat the metadata describes
+// so a tagged or random request doesn't roll a second, different cat.
+func (c *Client) imageURL(u *CatURL, meta *CatMetadata) (string, error) {
+	if len(u.params) == 0 && !u.hasSays {
+		return c.resolve(meta.URL)
+	}
+	if meta.ID == "" {
+		return "", fmt.Errorf("cannot render image: %w", ErrNoCatID)
+	}
+
+	imgURL := c.rebase(u)
+	imgURL.catID = meta.ID
+	imgURL.hasID = true
+	imgURL.tag = ""
+	imgURL.hasTag = false
+	imgURL.asJSON = false
+	imgURL.asHTML = false
+	return imgURL.Generate()
+}
+
+// Fetch requests the JSON metadata variant of u, then downloads the image it
+// describes with the same filters and text overlay applied. When u is pinned
+// to an id the server doesn't know, the error matches ErrCatNotFound. Both
+// downloads and their retries share the retry policy's MaxElapsed deadline.
+func (c *Client) Fetch(ctx context.Context, u *CatURL) (image.Image, *CatMetadata, error) {
+	if c.offline {
+		return c.fetchCached(u)
+	}
+	ctx, cancel := c.retry.withDeadline(ctx)
+	defer cancel()
+
+	// first get the metadata in JSON format
+	metaURL := c.rebase(u)
+	metaURL.asJSON = true
+	metaURL.asHTML = false
+	reqURL, err := metaURL.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var meta CatMetadata
+	if err := c.getJSON(ctx, reqURL, &meta); err != nil {
+		return nil, nil, catNotFound(u, err)
+	}
+
+	key := RenderKey(meta.ID, u)
+	if img, ok := c.cachedImage(meta.ID, key); ok {
+		return img, &meta, nil
+	}
+
+	// now get the actual image
+	imgURL, err := c.imageURL(u, &meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := c.cachedImageBytes(ctx, u, &meta, imgURL)
+	if err != nil {
+		return nil, nil, catNotFound(u, err)
+	}
+
+	img, err := c.decode(&meta, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.cacheImage(key, img, &meta)
+	return img, &meta, nil
+}
+
+// decode decodes the image described by meta, reporting a format mismatch
+func (c *Client) decode(meta *CatMetadata, data []byte) (image.Image, error) {
+	img, warning, err := Decode(meta.MIMEType, data, c.limits)
+	if err != nil {
+		log.Printf("Error decoding image: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrImageDecode, err)
+	}
+	if warning != nil && c.onWarning != nil {
+		c.onWarning(meta, *warning)
+	}
+	return img, nil
+}
+
+// do sends a GET with the extra header, if any. A 304 answer to a
+// conditional request is returned like a success.
+func (c *Client) do(ctx context.Context, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, classifyTransportError(err)
+	}
+	if resp.StatusCode == http.StatusNotModified && header != nil {
+		return resp, nil
+	}
+	if err := checkStatus(req.URL.String(), resp); err != nil {
+		closeBody(resp.Body)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, rawURL string, v any) error {
+	return c.retry.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.do(ctx, rawURL, nil)
+		if err != nil {
+			return err
+		}
+		defer closeBody(resp.Body)
+
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			if isTimeout(err) {
+				return classifyTransportError(err)
+			}
+			return fmt.Errorf("%w: %w", ErrMetadataDecode, err)
+		}
+		return nil
+	})
+}
+
+func (c *Client) getImageBytes(ctx context.Context, rawURL string) ([]byte, error) {
+	d, err := c.downloadImage(ctx, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return d.data, nil
+}
+
+// imageDownload is a downloaded image and the validators to revalidate it with
+type imageDownload struct {
+	data         []byte
+	etag         string
+	lastModified string
+	notModified  bool // the server confirmed the copy the validators came from, data is empty
+}
+
+// downloadImage downloads an image, conditionally when validators holds
+// If-None-Match or If-Modified-Since
+func (c *Client) downloadImage(ctx context.Context, rawURL string, validators http.Header) (imageDownload, error) {
+	var d imageDownload
+	err := c.retry.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.do(ctx, rawURL, validators)
+		if err != nil {
+			return err
+		}
+		defer closeBody(resp.Body)
+
+		d = imageDownload{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+		if resp.StatusCode == http.StatusNotModified {
+			d.notModified = true
+			return nil
+		}
+		if err := checkImageContentType(resp); err != nil {
+			return err
+		}
+
+		d.data, err = c.readImageBody(resp)
+		return err
+	})
+	return d, err
+}
+
+func logDecodeWarning(meta *CatMetadata, w DecodeWarning) {
+	log.Printf("Unexpected format for cat %s: %v", meta.ID, w)
+}
+
+func closeBody(body io.ReadCloser) {
+	if err := body.Close(); err != nil {
+		log.Printf("Error closing response body: %v", err)
+	}
+}
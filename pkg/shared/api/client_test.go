@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// newStandInServer creates a single server that answers both the metadata and the image requests
+func newStandInServer(t *testing.T, mimeType string, imageData []byte) *httptest.Server {
+	t.Helper()
+	return testutil.NewMockCatAPI(t, testutil.Routes{
+		"/cat": func(w http.ResponseWriter, r *http.Request) {
+			testutil.ServeJSON(w, fmt.Sprintf(`{"id":"stand_in","tags":["local"],"created_at":"2025-01-01T12:00:00Z","url":"/cat/stand_in","mimetype":"%s"}`, mimeType))
+		},
+		"/cat/{id}": func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("id") == "missing" {
+				http.Error(w, "Cat not found", http.StatusNotFound)
+				return
+			}
+			if r.URL.Query().Get("json") == "true" {
+				testutil.ServeJSON(w, fmt.Sprintf(`{"id":"%s","tags":[],"created_at":"2025-01-01T12:00:00Z","url":"/cat/%s","mimetype":"%s"}`, r.PathValue("id"), r.PathValue("id"), mimeType))
+				return
+			}
+			testutil.ServeImage(w, mimeType, imageData)
+		},
+		"/api/tags": func(w http.ResponseWriter, r *http.Request) {
+			testutil.ServeJSON(w, `["cute","orange","sleepy"]`)
+		},
+	})
+}
+
+// TestNewClient_Defaults tests the default client configuration
+func TestNewClient_Defaults(t *testing.T) {
+	c := NewClient()
+
+	testutil.AssertEqual(t, "https://cataas.com", c.BaseURL(), "base URL")
+	testutil.AssertEqual(t, defaultUserAgent, c.UserAgent(), "user agent")
+	testutil.AssertNil(t, c.httpClient.Transport, "transport should default to http.DefaultTransport")
+}
+
+// TestClient_WithMethodsCopy tests that the With* methods never modify the receiver
+func TestClient_WithMethodsCopy(t *testing.T) {
+	base := NewClient()
+
+	modified := base.WithBaseURL("http://localhost:1234/").
+		WithUserAgent("tests").
+		WithTimeout(time.Second).
+		WithTransport(http.DefaultTransport)
+
+	testutil.AssertEqual(t, "https://cataas.com", base.BaseURL(), "original base URL")
+	testutil.AssertEqual(t, defaultUserAgent, base.UserAgent(), "original user agent")
+	testutil.AssertEqual(t, time.Duration(0), base.httpClient.Timeout, "original timeout")
+	testutil.AssertNil(t, base.httpClient.Transport, "original transport")
+
+	testutil.AssertEqual(t, "http://localhost:1234", modified.BaseURL(), "trailing slash trimmed")
+	testutil.AssertEqual(t, "tests", modified.UserAgent(), "user agent")
+	testutil.AssertEqual(t, time.Second, modified.httpClient.Timeout, "timeout")
+	testutil.AssertNotNil(t, modified.httpClient.Transport, "transport")
+}
+
+// TestClient_ResolveURL tests making the server's relative URLs absolute
+func TestClient_ResolveURL(t *testing.T) {
+	c := NewClient().WithBaseURL("http://localhost:1234")
+
+	resolved, err := c.ResolveURL("/cat/abc123?fontSize=20")
+	testutil.AssertNoError(t, err, "ResolveURL should succeed")
+	testutil.AssertEqual(t, "http://localhost:1234/cat/abc123?fontSize=20", resolved, "relative URL")
+
+	resolved, err = c.ResolveURL("https://example.com/cat/abc123")
+	testutil.AssertNoError(t, err, "ResolveURL should succeed")
+	testutil.AssertEqual(t, "https://example.com/cat/abc123", resolved, "absolute URL kept")
+}
+
+// TestClient_RandomCat tests fetching a random cat from a stand-in server
+func TestClient_RandomCat(t *testing.T) {
+	server := newStandInServer(t, "image/png", testutil.ValidPNGBytes())
+	c := NewClient().WithBaseURL(server.URL)
+
+	t.Run("nil_cat_url", func(t *testing.T) {
+		img, meta, err := c.RandomCat(context.Background(), nil)
+		testutil.AssertNoError(t, err, "RandomCat should succeed")
+		testutil.AssertNotNil(t, img, "image should not be nil")
+		testutil.AssertEqual(t, "stand_in", meta.GetID(), "ID")
+	})
+
+	t.Run("explicit_cat_url", func(t *testing.T) {
+		img, meta, err := c.RandomCat(context.Background(), NewCatURL())
+		testutil.AssertNoError(t, err, "RandomCat should succeed")
+		testutil.AssertNotNil(t, img, "image should not be nil")
+		testutil.AssertEqual(t, "image/png", meta.GetMIMEType(), "MIME type")
+	})
+}
+
+// TestClient_CatByID tests fetching a specific cat
+func TestClient_CatByID(t *testing.T) {
+	server := newStandInServer(t, "image/gif", testutil.ValidGIFBytes())
+	c := NewClient().WithBaseURL(server.URL)
+
+	t.Run("found", func(t *testing.T) {
+		img, meta, err := c.CatByID(context.Background(), "abc123")
+
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+		testutil.AssertNotNil(t, img, "image should not be nil")
+		testutil.AssertEqual(t, "abc123", meta.GetID(), "ID")
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		img, meta, err := c.CatByID(context.Background(), "missing")
+
+		testutil.AssertTrue(t, errors.Is(err, ErrCatNotFound), "should be ErrCatNotFound")
+		testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "status error kept in the chain")
+		testutil.AssertNil(t, img, "no image")
+		testutil.AssertNil(t, meta, "no metadata")
+	})
+
+	t.Run("not_found_with_options", func(t *testing.T) {
+		_, _, err := c.Fetch(context.Background(), NewCatURL().WithID("missing").WithWidth(10))
+		testutil.AssertTrue(t, errors.Is(err, ErrCatNotFound), "should be ErrCatNotFound")
+	})
+
+	t.Run("empty_id", func(t *testing.T) {
+		_, _, err := c.CatByID(context.Background(), "")
+		testutil.AssertTrue(t, errors.Is(err, ErrEmptyID), "should be rejected before any request")
+	})
+
+	t.Run("random_404_is_not_not_found", func(t *testing.T) {
+		_, _, err := c.WithBaseURL(server.URL+"/nowhere").RandomCat(context.Background(), nil)
+		testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "should be a status error")
+		testutil.AssertFalse(t, errors.Is(err, ErrCatNotFound), "only id requests are not found")
+	})
+
+	t.Run("default_client", func(t *testing.T) {
+		old := DefaultClient
+		DefaultClient = c
+		t.Cleanup(func() { DefaultClient = old })
+
+		_, meta, err := FetchCatByID(context.Background(), "xyz")
+		testutil.AssertNoError(t, err, "FetchCatByID should succeed")
+		testutil.AssertEqual(t, "xyz", meta.GetID(), "ID")
+	})
+}
+
+// TestClient_Tags tests fetching the tag list
+func TestClient_Tags(t *testing.T) {
+	server := newStandInServer(t, "image/png", testutil.ValidPNGBytes())
+	c := NewClient().WithBaseURL(server.URL)
+
+	tags, err := c.Tags(context.Background())
+
+	testutil.AssertNoError(t, err, "Tags should succeed")
+	testutil.AssertEqual(t, 3, len(tags), "tags length")
+	testutil.AssertEqual(t, "orange", tags[1], "second tag")
+}
+
+// TestClient_UserAgent tests that the user agent is sent with each request
+func TestClient_UserAgent(t *testing.T) {
+	var got string
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		got = r.UserAgent()
+		w.Write([]byte(`[]`))
+	}})
+
+	_, err := NewClient().WithBaseURL(server.URL).WithUserAgent("catfetch-test/1.0").Tags(context.Background())
+
+	testutil.AssertNoError(t, err, "Tags should succeed")
+	testutil.AssertEqual(t, "catfetch-test/1.0", got, "user agent header")
+}
+
+// TestClient_Transport tests that an injected RoundTripper is used
+func TestClient_Transport(t *testing.T) {
+	server := newStandInServer(t, "image/png", testutil.ValidPNGBytes())
+
+	var calls int
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	_, _, err := NewClient().WithBaseURL(server.URL).WithTransport(rt).RandomCat(context.Background(), nil)
+
+	testutil.AssertNoError(t, err, "RandomCat should succeed")
+	testutil.AssertEqual(t, 2, calls, "metadata and image should both go through the transport")
+}
+
+// TestClient_ContextCancel tests that an in-flight fetch stops when the context is cancelled
+func TestClient_ContextCancel(t *testing.T) {
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	img, meta, err := NewClient().WithBaseURL(server.URL).RandomCat(ctx, nil)
+
+	testutil.AssertError(t, err, "cancelled fetch should fail")
+	testutil.AssertTrue(t, errors.Is(err, context.Canceled), "error should be context.Canceled")
+	testutil.AssertNil(t, img, "image should be nil")
+	testutil.AssertNil(t, meta, "metadata should be nil")
+}
+
+// TestClient_Transport_ErrorStatus tests a failure from an injected
+// RoundTripper that, like many stand-ins, leaves resp.Request unset
+func TestClient_Transport_ErrorStatus(t *testing.T) {
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("down")),
+		}, nil
+	})
+
+	var err error
+	testutil.AssertNoPanic(t, func() {
+		_, err = NewClient().WithTransport(rt).WithRetryPolicy(NoRetry).Tags(context.Background())
+	}, "Tags should not panic")
+
+	var statusErr *HTTPStatusError
+	testutil.AssertTrue(t, errors.As(err, &statusErr), "should be an *HTTPStatusError")
+	testutil.AssertEqual(t, http.StatusServiceUnavailable, statusErr.StatusCode, "status")
+	testutil.AssertEqual(t, "https://cataas.com/api/tags", statusErr.URL, "URL of the request")
+	testutil.AssertEqual(t, "down", statusErr.Body, "body")
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestClient_Fetch_AppliesOptions tests that the image is downloaded with the CatURL's options applied
+func TestClient_Fetch_AppliesOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		catURL       *CatURL
+		expectedMeta string
+		expectedPath string
+		expectedRaw  string
+	}{
+		{
+			name:         "filter_and_size",
+			catURL:       NewCatURL().WithCAASImageFilter(CAASImageFilterMono).WithWidth(200),
+			expectedMeta: "filter=mono&json=true&width=200",
+			expectedPath: "/cat/rendered",
+			expectedRaw:  "filter=mono&width=200",
+		},
+		{
+			name:         "text_overlay",
+			catURL:       NewCatURL().WithSays("hello").WithFontSize(30),
+			expectedMeta: "fontSize=30&json=true",
+			expectedPath: "/cat/rendered/says/hello",
+			expectedRaw:  "fontSize=30",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metaQuery, imagePath, imageQuery string
+			server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("json") == "true" {
+					metaQuery = r.URL.RawQuery
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"id":"rendered","tags":[],"url":"/cat/rendered","mimetype":"image/png"}`))
+					return
+				}
+				imagePath = r.URL.Path
+				imageQuery = r.URL.RawQuery
+				w.Header().Set("Content-Type", "image/png")
+				w.Write(testutil.ValidPNGBytes())
+			}})
+
+			img, meta, err := NewClient().WithBaseURL(server.URL).Fetch(context.Background(), tt.catURL)
+
+			testutil.AssertNoError(t, err, "Fetch should succeed")
+			testutil.AssertNotNil(t, img, "image should not be nil")
+			testutil.AssertEqual(t, "rendered", meta.GetID(), "ID")
+			testutil.AssertEqual(t, tt.expectedMeta, metaQuery, "metadata request keeps the options")
+			testutil.AssertEqual(t, tt.expectedPath, imagePath, "image path pins the cat id")
+			testutil.AssertEqual(t, tt.expectedRaw, imageQuery, "image request keeps the options")
+		})
+	}
+}
+
+// TestClient_Fetch_TaggedCatPinsID tests that a tagged request downloads the cat named in the metadata
+func TestClient_Fetch_TaggedCatPinsID(t *testing.T) {
+	var imagePath string
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("json") == "true" {
+			w.Write([]byte(`{"id":"tagged_cat","tags":["orange"],"url":"/cat/tagged_cat","mimetype":"image/png"}`))
+			return
+		}
+		imagePath = r.URL.Path
+		w.Write(testutil.ValidPNGBytes())
+	}})
+
+	u := NewCatURL().WithCAASImageType(CAASImageTypeSmall)
+	u.tag, u.hasTag = "orange", true
+
+	_, _, err := NewClient().WithBaseURL(server.URL).Fetch(context.Background(), u)
+
+	testutil.AssertNoError(t, err, "Fetch should succeed")
+	testutil.AssertEqual(t, "/cat/tagged_cat", imagePath, "image path should use the id, not the tag")
+}
+
+// TestClient_Fetch_MissingID tests that rendering options need a cat id to download the image
+func TestClient_Fetch_MissingID(t *testing.T) {
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"/cat/whatever","mimetype":"image/png"}`))
+	}})
+
+	_, _, err := NewClient().WithBaseURL(server.URL).Fetch(context.Background(), NewCatURL().WithBlur(3))
+
+	testutil.AssertTrue(t, errors.Is(err, ErrNoCatID), "should fail with ErrNoCatID")
+}
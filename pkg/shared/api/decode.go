@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = fmt.Errorf("unsupported image format")
+	ErrImageTooLarge     = fmt.Errorf("image too large")
+)
+
+// Decoder decodes the complete bytes of one image format. DecodeConfig is
+// called first so oversized images are refused before any pixels are
+// allocated. Decoders for animated formats return an *Animation when there
+// is more than one frame, and check the pixels of all frames against limits
+// before compositing them.
+type Decoder interface {
+	DecodeConfig(data []byte) (image.Config, error)
+	Decode(data []byte, limits DecodeLimits) (image.Image, error)
+}
+
+type decoder struct {
+	decode       func(data []byte, limits DecodeLimits) (image.Image, error)
+	decodeConfig func(r io.Reader) (image.Config, error)
+}
+
+func (d decoder) Decode(data []byte, limits DecodeLimits) (image.Image, error) {
+	return d.decode(data, limits)
+}
+
+func (d decoder) DecodeConfig(data []byte) (image.Config, error) {
+	return d.decodeConfig(bytes.NewReader(data))
+}
+
+// ReaderDecoder makes a Decoder from the function pair an image format
+// package provides, e.g. ReaderDecoder(bmp.Decode, bmp.DecodeConfig). It
+// suits formats with a single frame, which DecodeConfig fully describes.
+func ReaderDecoder(decode func(io.Reader) (image.Image, error), decodeConfig func(io.Reader) (image.Config, error)) Decoder {
+	return decoder{
+		decode: func(data []byte, _ DecodeLimits) (image.Image, error) {
+			return decode(bytes.NewReader(data))
+		},
+		decodeConfig: decodeConfig,
+	}
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"image/gif":  decoder{decode: decodeGIF, decodeConfig: gif.DecodeConfig},
+		"image/webp": decoder{decode: decodeWebP, decodeConfig: webp.DecodeConfig},
+		"image/jpeg": ReaderDecoder(jpeg.Decode, jpeg.DecodeConfig),
+		"image/png":  ReaderDecoder(png.Decode, png.DecodeConfig),
+	}
+)
+
+// RegisterDecoder makes d responsible for images of mimeType, replacing any
+// decoder registered for it before. Formats that http.DetectContentType
+// doesn't recognise are only picked by the MIME type the server declares.
+func RegisterDecoder(mimeType string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[normalizeMIMEType(mimeType)] = d
+}
+
+func lookupDecoder(mimeType string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	d, ok := decoders[normalizeMIMEType(mimeType)]
+	return d, ok
+}
+
+// normalizeMIMEType drops parameters and case, "Image/GIF; q=1" -> "image/gif"
+func normalizeMIMEType(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// DecodeLimits guard against decompression bombs, small files that expand to
+// huge images. Zero fields mean no limit.
+type DecodeLimits struct {
+	MaxBytes  int64 // size of the encoded image, also the cap on downloading it
+	MaxPixels int64 // width * height of the image, summed over the frames of an animation
+}
+
+// DefaultDecodeLimits are the limits used by NewClient
+func DefaultDecodeLimits() DecodeLimits {
+	return DecodeLimits{
+		MaxBytes:  32 << 20,
+		MaxPixels: 64 << 20,
+	}
+}
+
+func (l DecodeLimits) checkBytes(n int64) error {
+	if l.MaxBytes > 0 && n > l.MaxBytes {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrImageTooLarge, n, l.MaxBytes)
+	}
+	return nil
+}
+
+func (l DecodeLimits) checkConfig(cfg image.Config) error {
+	return l.checkFrames(image.Rect(0, 0, cfg.Width, cfg.Height), 1)
+}
+
+// checkFrames checks the pixels of an animation composited onto canvas
+func (l DecodeLimits) checkFrames(canvas image.Rectangle, frames int) error {
+	if l.MaxPixels > 0 && int64(canvas.Dx())*int64(canvas.Dy())*int64(frames) > l.MaxPixels {
+		return fmt.Errorf("%w: %d frames of %dx%d exceed the limit of %d pixels",
+			ErrImageTooLarge, frames, canvas.Dx(), canvas.Dy(), l.MaxPixels)
+	}
+	return nil
+}
+
+// DecodeWarning reports an image that decoded, but not as the format the
+// server declared for it.
+type DecodeWarning struct {
+	Declared string // MIME type from the metadata
+	Sniffed  string // MIME type http.DetectContentType sees in the bytes
+	Decoded  string // MIME type of the decoder that succeeded
+	Err      error  // why the decoders tried before it failed, if any did
+}
+
+func (w DecodeWarning) String() string {
+	msg := fmt.Sprintf("declared %q, sniffed %q, decoded as %q", w.Declared, w.Sniffed, w.Decoded)
+	if w.Err != nil {
+		msg += ": " + w.Err.Error()
+	}
+	return msg
+}
+
+// Decode decodes data, trying the decoder for the sniffed content type
+// before the one for the declared mimeType, then any format registered with
+// the image package. The warning is non-nil when the image decoded as a
+// format other than the declared one.
+func Decode(mimeType string, data []byte, limits DecodeLimits) (image.Image, *DecodeWarning, error) {
+	if err := limits.checkBytes(int64(len(data))); err != nil {
+		return nil, nil, err
+	}
+
+	declared := normalizeMIMEType(mimeType)
+	sniffed := normalizeMIMEType(http.DetectContentType(data))
+	warning := func(decoded string, errs []error) *DecodeWarning {
+		if decoded == declared {
+			return nil
+		}
+		return &DecodeWarning{Declared: declared, Sniffed: sniffed, Decoded: decoded, Err: errors.Join(errs...)}
+	}
+
+	var errs []error
+	candidates := []string{sniffed}
+	if declared != sniffed {
+		candidates = append(candidates, declared)
+	}
+	for _, candidate := range candidates {
+		d, ok := lookupDecoder(candidate)
+		if !ok {
+			continue
+		}
+		img, err := decodeLimited(d, data, limits)
+		if err == nil {
+			return img, warning(candidate, errs), nil
+		}
+		if errors.Is(err, ErrImageTooLarge) {
+			return nil, nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+	}
+
+	// formats the application only registered with the image package
+	if cfg, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		if err := limits.checkConfig(cfg); err != nil {
+			return nil, nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err == nil {
+			return img, warning("image/"+format, errs), nil
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return nil, nil, fmt.Errorf("%w: declared %q, sniffed %q", ErrUnsupportedFormat, declared, sniffed)
+}
+
+func decodeLimited(d Decoder, data []byte, limits DecodeLimits) (image.Image, error) {
+	cfg, err := d.DecodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.checkConfig(cfg); err != nil {
+		return nil, err
+	}
+	return d.Decode(data, limits)
+}
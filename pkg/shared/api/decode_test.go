@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// stubDecoder is a Decoder for a made up format
+type stubDecoder struct {
+	cfg     image.Config
+	decoded int
+}
+
+func (d *stubDecoder) DecodeConfig(data []byte) (image.Config, error) {
+	return d.cfg, nil
+}
+
+func (d *stubDecoder) Decode(data []byte, limits DecodeLimits) (image.Image, error) {
+	d.decoded++
+	return image.NewRGBA(image.Rect(0, 0, d.cfg.Width, d.cfg.Height)), nil
+}
+
+// withDecoder registers d for the duration of the test
+func withDecoder(t *testing.T, mimeType string, d Decoder) {
+	t.Helper()
+	decodersMu.Lock()
+	previous, existed := decoders[mimeType]
+	decodersMu.Unlock()
+	RegisterDecoder(mimeType, d)
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		if existed {
+			decoders[mimeType] = previous
+		} else {
+			delete(decoders, mimeType)
+		}
+	})
+}
+
+// jpegBytes encodes a small JPEG, testutil.ValidJPEGBytes only has headers
+func jpegBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, testutil.CreateColorImage(4, 4, 200, 0, 0), nil)
+	testutil.AssertNoError(t, err, "jpeg.Encode should succeed")
+	return buf.Bytes()
+}
+
+// TestDecode tests decoder selection by sniffed and declared MIME type
+func TestDecode(t *testing.T) {
+	webpData := testutil.WebPBytes(2, 2, color.NRGBA{R: 255, A: 255})
+
+	tests := []struct {
+		name     string
+		declared string
+		data     []byte
+		warning  *DecodeWarning
+	}{
+		{"declared", "image/webp", webpData, nil},
+		{"parameters_and_case", "Image/WebP; charset=binary", webpData, nil},
+		{"jpeg", "image/jpeg", jpegBytes(t), nil},
+		{"gif", "image/gif", testutil.ValidGIFBytes(), nil},
+		{"mislabelled", "image/jpeg", webpData, &DecodeWarning{Declared: "image/jpeg", Sniffed: "image/webp", Decoded: "image/webp"}},
+		{"undeclared", "", testutil.ValidPNGBytes(), &DecodeWarning{Declared: "", Sniffed: "image/png", Decoded: "image/png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, warning, err := Decode(tt.declared, tt.data, DefaultDecodeLimits())
+			testutil.AssertNoError(t, err, "Decode should succeed")
+			testutil.AssertNotNil(t, img, "image")
+			testutil.AssertEqual(t, tt.warning, warning, "warning")
+		})
+	}
+}
+
+// TestDecode_Errors tests data no decoder accepts
+func TestDecode_Errors(t *testing.T) {
+	t.Run("unsupported", func(t *testing.T) {
+		_, _, err := Decode("image/avif", []byte("not an image at all"), DecodeLimits{})
+		testutil.AssertTrue(t, errors.Is(err, ErrUnsupportedFormat), "should be ErrUnsupportedFormat")
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		data := testutil.ValidPNGBytes()
+		_, _, err := Decode("image/png", data[:len(data)/2], DecodeLimits{})
+		testutil.AssertError(t, err, "Decode should fail")
+		testutil.AssertFalse(t, errors.Is(err, ErrUnsupportedFormat), "a known format is not unsupported")
+	})
+}
+
+// TestDecode_Limits tests refusing oversized images
+func TestDecode_Limits(t *testing.T) {
+	png := testutil.ValidPNGBytes() // 1x1
+
+	t.Run("bytes", func(t *testing.T) {
+		_, _, err := Decode("image/png", png, DecodeLimits{MaxBytes: int64(len(png)) - 1})
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+
+		_, _, err = Decode("image/png", png, DecodeLimits{MaxBytes: int64(len(png))})
+		testutil.AssertNoError(t, err, "an image at the limit should decode")
+	})
+
+	t.Run("pixels", func(t *testing.T) {
+		webpData := testutil.WebPBytes(4, 4, color.NRGBA{A: 255})
+		_, _, err := Decode("image/webp", webpData, DecodeLimits{MaxPixels: 15})
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+
+		_, _, err = Decode("image/webp", webpData, DecodeLimits{MaxPixels: 16})
+		testutil.AssertNoError(t, err, "an image at the limit should decode")
+	})
+
+	t.Run("checked_before_decoding", func(t *testing.T) {
+		bomb := &stubDecoder{cfg: image.Config{Width: 100000, Height: 100000}}
+		withDecoder(t, "image/x-bomb", bomb)
+
+		_, _, err := Decode("image/x-bomb", []byte("tiny"), DefaultDecodeLimits())
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+		testutil.AssertEqual(t, 0, bomb.decoded, "Decode calls")
+	})
+
+	t.Run("animation_canvas", func(t *testing.T) {
+		data := testutil.AnimatedWebPBytes(8, 8, 0,
+			testutil.WebPFrame{Width: 1, Height: 1, Color: color.NRGBA{A: 255}, Duration: 50},
+			testutil.WebPFrame{Width: 1, Height: 1, Color: color.NRGBA{A: 255}, Duration: 50},
+		)
+		// the canvas fits, both frames composited onto it don't
+		_, _, err := Decode("image/webp", data, DecodeLimits{MaxPixels: 100})
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "webp should be ErrImageTooLarge")
+
+		gifData, err := testutil.AnimatedGIFBytes(8, 8, 10, gif.DisposalNone, color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255})
+		testutil.AssertNoError(t, err, "AnimatedGIFBytes should succeed")
+		_, _, err = Decode("image/gif", gifData, DecodeLimits{MaxPixels: 100})
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "gif should be ErrImageTooLarge")
+
+		_, _, err = Decode("image/gif", gifData, DecodeLimits{MaxPixels: 128})
+		testutil.AssertNoError(t, err, "an animation at the limit should decode")
+	})
+}
+
+// TestRegisterDecoder tests adding a format
+func TestRegisterDecoder(t *testing.T) {
+	stub := &stubDecoder{cfg: image.Config{Width: 7, Height: 7}}
+	withDecoder(t, "Image/X-Test", stub)
+
+	img, warning, err := Decode("image/x-test", []byte("anything"), DefaultDecodeLimits())
+	testutil.AssertNoError(t, err, "Decode should succeed")
+	testutil.AssertNil(t, warning, "warning")
+	testutil.AssertImageDimensions(t, img, 7, 7)
+	testutil.AssertEqual(t, 1, stub.decoded, "Decode calls")
+
+	t.Run("reader_decoder", func(t *testing.T) {
+		want := image.Config{Width: 3, Height: 5}
+		withDecoder(t, "image/x-reader", ReaderDecoder(
+			func(r io.Reader) (image.Image, error) { return image.NewGray(image.Rect(0, 0, 3, 5)), nil },
+			func(r io.Reader) (image.Config, error) { return want, nil },
+		))
+		img, _, err := Decode("image/x-reader", []byte("anything"), DefaultDecodeLimits())
+		testutil.AssertNoError(t, err, "Decode should succeed")
+		testutil.AssertImageDimensions(t, img, 3, 5)
+	})
+}
+
+// TestClient_DecodeWarnings tests reporting a mislabelled image
+func TestClient_DecodeWarnings(t *testing.T) {
+	server := newStandInServer(t, "image/jpeg", testutil.ValidPNGBytes())
+
+	var got []DecodeWarning
+	client := NewClient().WithBaseURL(server.URL).WithDecodeWarningHandler(func(meta *CatMetadata, w DecodeWarning) {
+		got = append(got, w)
+	})
+	_, _, err := client.CatByID(context.Background(), "mislabelled")
+	testutil.AssertNoError(t, err, "CatByID should succeed")
+	testutil.AssertEqual(t, 1, len(got), "warnings")
+	testutil.AssertEqual(t, "image/png", got[0].Decoded, "decoded format")
+
+	t.Run("limits", func(t *testing.T) {
+		server := newStandInServer(t, "image/webp", testutil.WebPBytes(4, 4, color.NRGBA{A: 255}))
+		client := NewClient().WithBaseURL(server.URL).WithDecodeLimits(DecodeLimits{MaxPixels: 15})
+		_, _, err := client.CatByID(context.Background(), "big")
+		testutil.AssertTrue(t, errors.Is(err, ErrImageDecode), "should be ErrImageDecode")
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+	})
+}
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDiskCacheSize   = 256 << 20
+	diskCacheDirName       = "cats"
+	diskCacheIndexFileName = "index.json"
+	diskCacheBlobDirName   = "blobs"
+
+	// diskCacheSaveInterval is how often reading cached images rewrites the
+	// index to record their access times, which otherwise wait for the next
+	// change or Flush
+	diskCacheSaveInterval = 30 * time.Second
+)
+
+var ErrNotCached = fmt.Errorf("cat not in cache")
+
+// DiskCache keeps fetched cat images on disk so a cat that was seen before
+// only needs a revalidation instead of a download. Image bytes are stored
+// under their sha256, so renderings that happen to be identical share a
+// file, and an index maps each cat id and set of rendering options to its
+// bytes, metadata and HTTP validators. When the stored images outgrow the
+// size limit the least recently used ones are evicted.
+type DiskCache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	entries  map[string]*diskCacheEntry
+	loaded   bool
+	dirty    bool      // access times changed since the index was saved
+	savedAt  time.Time // when the index was last saved
+	now      func() time.Time
+}
+
+type diskCacheEntry struct {
+	Key          string      `json:"key"`
+	Options      string      `json:"options"` // rendering options, Key without the cat id
+	Metadata     CatMetadata `json:"metadata"`
+	Blob         string      `json:"blob"` // sha256 of the image, also its file name
+	Size         int64       `json:"size"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	AccessedAt   time.Time   `json:"accessed_at"`
+}
+
+// diskCacheIndex is the on-disk format of the cache index
+type diskCacheIndex struct {
+	Entries []*diskCacheEntry `json:"entries"`
+}
+
+// NewDiskCache creates a cache in dir holding at most maxBytes of images,
+// a maxBytes of 0 means no limit. Nothing is read until it is first used.
+func NewDiskCache(dir string, maxBytes int64) *DiskCache {
+	return &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*diskCacheEntry),
+		now:      time.Now,
+	}
+}
+
+// DefaultDiskCacheDir returns the image cache location in the user's cache
+// directory, or "" when there is none.
+func DefaultDiskCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, tagCacheDirName, diskCacheDirName)
+}
+
+// renderOptions is the part of the image URL for u that isn't the server or
+// the cat id, e.g. "/says/hi?fontSize=30". Together with the id it names
+// one rendering of a cat.
+func renderOptions(u *CatURL) string {
+	cp := u.clone()
+	cp.baseURL = ""
+	cp.catID, cp.hasID = "", false
+	cp.tag, cp.hasTag = "", false
+	cp.asJSON, cp.asHTML = false, false
+	options, err := cp.Generate()
+	if err != nil {
+		return ""
+	}
+	return options
+}
+
+func diskCacheKey(id, options string) string {
+	return id + options
+}
+
+// Len returns the number of cached renderings
+func (d *DiskCache) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+	return len(d.entries)
+}
+
+// Size returns the bytes of images in the cache
+func (d *DiskCache) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+	return d.size()
+}
+
+// Clear removes every cached image
+func (d *DiskCache) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = make(map[string]*diskCacheEntry)
+	d.loaded = true
+	if err := os.RemoveAll(filepath.Join(d.dir, diskCacheBlobDirName)); err != nil {
+		return err
+	}
+	return d.save()
+}
+
+// load reads the index the first time the cache is used. A missing or
+// unreadable index leaves the cache empty.
+func (d *DiskCache) load() {
+	if d.loaded {
+		return
+	}
+	d.loaded = true
+
+	data, err := os.ReadFile(filepath.Join(d.dir, diskCacheIndexFileName))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error loading image cache: %v", err)
+		}
+		return
+	}
+	var index diskCacheIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		log.Printf("Error loading image cache: %s: %v", d.dir, err)
+		return
+	}
+	for _, e := range index.Entries {
+		if e != nil && e.Key != "" && e.Blob != "" {
+			d.entries[e.Key] = e
+		}
+	}
+}
+
+// Flush saves access times recorded since the index was last written, so
+// the least recently used order survives a restart. Call it before exiting.
+func (d *DiskCache) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.dirty {
+		return nil
+	}
+	return d.save()
+}
+
+func (d *DiskCache) save() error {
+	index := diskCacheIndex{Entries: slices.Collect(maps.Values(d.entries))}
+	slices.SortFunc(index.Entries, func(a, b *diskCacheEntry) int {
+		return a.AccessedAt.Compare(b.AccessedAt)
+	})
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(d.dir, diskCacheIndexFileName), data); err != nil {
+		return err
+	}
+	d.dirty = false
+	d.savedAt = d.now()
+	return nil
+}
+
+// size counts each stored image once, however many entries share it
+func (d *DiskCache) size() int64 {
+	var total int64
+	seen := make(map[string]bool, len(d.entries))
+	for _, e := range d.entries {
+		if !seen[e.Blob] {
+			seen[e.Blob] = true
+			total += e.Size
+		}
+	}
+	return total
+}
+
+func (d *DiskCache) blobPath(blob string) string {
+	return filepath.Join(d.dir, diskCacheBlobDirName, blob)
+}
+
+// lookup returns the entry for key without touching it
+func (d *DiskCache) lookup(key string) (diskCacheEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+	e, ok := d.entries[key]
+	if !ok {
+		return diskCacheEntry{}, false
+	}
+	return *e, true
+}
+
+// random picks any entry rendered with options, and tagged tag unless tag is empty
+func (d *DiskCache) random(options, tag string) (diskCacheEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+	var matches []*diskCacheEntry
+	for _, e := range d.entries {
+		if e.Options == options && (tag == "" || slices.Contains(e.Metadata.Tags, tag)) {
+			matches = append(matches, e)
+		}
+	}
+	if len(matches) == 0 {
+		return diskCacheEntry{}, false
+	}
+	return *matches[rand.IntN(len(matches))], true
+}
+
+// read returns the image bytes of an entry and marks it as recently used.
+// The index is saved at most every diskCacheSaveInterval for that. An entry
+// whose file is gone or doesn't match its hash is dropped.
+func (d *DiskCache) read(key string) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+	e, ok := d.entries[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotCached, key)
+	}
+
+	data, err := os.ReadFile(d.blobPath(e.Blob))
+	if err == nil && blobName(data) != e.Blob {
+		err = fmt.Errorf("%s: contents don't match their hash", d.blobPath(e.Blob))
+	}
+	if err != nil {
+		d.remove(key)
+		if saveErr := d.save(); saveErr != nil {
+			log.Printf("Error saving image cache: %v", saveErr)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrNotCached, err)
+	}
+
+	e.AccessedAt = d.now()
+	d.dirty = true
+	if e.AccessedAt.Sub(d.savedAt) >= diskCacheSaveInterval {
+		if err := d.save(); err != nil {
+			log.Printf("Error saving image cache: %v", err)
+		}
+	}
+	return data, nil
+}
+
+// put stores data for the entry, replacing what was cached under its key,
+// then evicts the least recently used images until the cache fits its limit
+func (d *DiskCache) put(e diskCacheEntry, data []byte) error {
+	if d.maxBytes > 0 && int64(len(data)) > d.maxBytes {
+		return nil // would evict everything and still not fit
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+
+	// drop the old entry first, removing it can delete a file the new one shares
+	d.remove(e.Key)
+	e.Blob = blobName(data)
+	e.Size = int64(len(data))
+	e.AccessedAt = d.now()
+	if _, err := os.Stat(d.blobPath(e.Blob)); err != nil {
+		if err := writeFileAtomic(d.blobPath(e.Blob), data); err != nil {
+			return err
+		}
+	}
+	d.entries[e.Key] = &e
+
+	d.evict()
+	return d.save()
+}
+
+// revalidated records the validators the server sent with a 304 for an
+// entry, when they changed
+func (d *DiskCache) revalidated(key, etag, lastModified string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load()
+	e, ok := d.entries[key]
+	if !ok || (etag == "" || etag == e.ETag) && (lastModified == "" || lastModified == e.LastModified) {
+		return
+	}
+	if etag != "" {
+		e.ETag = etag
+	}
+	if lastModified != "" {
+		e.LastModified = lastModified
+	}
+	if err := d.save(); err != nil {
+		log.Printf("Error saving image cache: %v", err)
+	}
+}
+
+func (d *DiskCache) evict() {
+	if d.maxBytes <= 0 {
+		return
+	}
+	for d.size() > d.maxBytes {
+		var oldest *diskCacheEntry
+		for _, e := range d.entries {
+			if oldest == nil || e.AccessedAt.Before(oldest.AccessedAt) {
+				oldest = e
+			}
+		}
+		d.remove(oldest.Key)
+	}
+}
+
+// remove drops an entry, and its file once no other entry shares it
+func (d *DiskCache) remove(key string) {
+	e, ok := d.entries[key]
+	if !ok {
+		return
+	}
+	delete(d.entries, key)
+	for _, other := range d.entries {
+		if other.Blob == e.Blob {
+			return
+		}
+	}
+	if err := os.Remove(d.blobPath(e.Blob)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error removing cached image: %v", err)
+	}
+}
+
+// cachedImageBytes downloads the image at imgURL through the disk cache. A
+// cached copy is revalidated with the server and only downloaded again when
+// it changed.
+func (c *Client) cachedImageBytes(ctx context.Context, u *CatURL, meta *CatMetadata, imgURL string) ([]byte, error) {
+	if c.cache == nil || meta.ID == "" {
+		return c.getImageBytes(ctx, imgURL)
+	}
+
+	options := renderOptions(u)
+	key := diskCacheKey(meta.ID, options)
+	var validators http.Header
+	if cached, ok := c.cache.lookup(key); ok && (cached.ETag != "" || cached.LastModified != "") {
+		validators = make(http.Header)
+		if cached.ETag != "" {
+			validators.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			validators.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	d, err := c.downloadImage(ctx, imgURL, validators)
+	if err != nil {
+		return nil, err
+	}
+	if d.notModified {
+		data, err := c.cache.read(key)
+		if err == nil {
+			c.cache.revalidated(key, d.etag, d.lastModified)
+			return data, nil
+		}
+		log.Printf("Error reading cached image, downloading it again: %v", err)
+		if d, err = c.downloadImage(ctx, imgURL, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := diskCacheEntry{Key: key, Options: options, Metadata: *meta, ETag: d.etag, LastModified: d.lastModified}
+	if err := c.cache.put(entry, d.data); err != nil {
+		log.Printf("Error caching image: %v", err)
+	}
+	return d.data, nil
+}
+
+// fetchCached serves u from the disk cache without going to the network. A
+// request pinned to an id needs that exact rendering cached, any other picks
+// one of the cached cats with the same options and tag.
+func (c *Client) fetchCached(u *CatURL) (image.Image, *CatMetadata, error) {
+	if c.cache == nil {
+		return nil, nil, fmt.Errorf("%w: offline without a disk cache", ErrNotCached)
+	}
+	if err := u.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	options := renderOptions(u)
+	var entry diskCacheEntry
+	var ok bool
+	if u.hasID {
+		entry, ok = c.cache.lookup(diskCacheKey(u.catID, options))
+	} else {
+		entry, ok = c.cache.random(options, u.tag)
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotCached, diskCacheKey(u.catID, options))
+	}
+
+	if img, ok := c.cachedImage(entry.Metadata.ID, entry.Key); ok {
+		return img, &entry.Metadata, nil
+	}
+	data, err := c.cache.read(entry.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := c.decode(&entry.Metadata, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.cacheImage(entry.Key, img, &entry.Metadata)
+	return img, &entry.Metadata, nil
+}
+
+func blobName(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes then renames so a crash never leaves a half written file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
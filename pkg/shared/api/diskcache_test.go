@@ -0,0 +1,386 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// newTestDiskCache returns a cache in a temporary directory with a clock
+// that advances a second on every use
+func newTestDiskCache(t *testing.T, maxBytes int64) *DiskCache {
+	t.Helper()
+	d := NewDiskCache(t.TempDir(), maxBytes)
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	return d
+}
+
+func putEntry(t *testing.T, d *DiskCache, id string, data []byte) {
+	t.Helper()
+	e := diskCacheEntry{Key: diskCacheKey(id, ""), Metadata: CatMetadata{ID: id, MIMEType: "image/png"}}
+	testutil.AssertNoError(t, d.put(e, data), "put should succeed")
+}
+
+// TestDiskCache_PutRead tests storing and reading back an image
+func TestDiskCache_PutRead(t *testing.T) {
+	d := newTestDiskCache(t, 0)
+	png := testutil.ValidPNGBytes()
+	putEntry(t, d, "abc", png)
+
+	data, err := d.read("abc")
+	testutil.AssertNoError(t, err, "read should succeed")
+	testutil.AssertEqual(t, string(png), string(data), "cached bytes")
+	testutil.AssertEqual(t, 1, d.Len(), "entries")
+	testutil.AssertEqual(t, int64(len(png)), d.Size(), "size")
+
+	_, err = d.read("missing")
+	testutil.AssertTrue(t, errors.Is(err, ErrNotCached), "should be ErrNotCached")
+
+	t.Run("persists", func(t *testing.T) {
+		reopened := NewDiskCache(d.dir, 0)
+		e, ok := reopened.lookup("abc")
+		testutil.AssertTrue(t, ok, "entry should survive reopening")
+		testutil.AssertEqual(t, "image/png", e.Metadata.MIMEType, "metadata")
+		data, err := reopened.read("abc")
+		testutil.AssertNoError(t, err, "read should succeed")
+		testutil.AssertEqual(t, string(png), string(data), "cached bytes")
+	})
+}
+
+// TestDiskCache_Eviction tests evicting the least recently used images
+func TestDiskCache_Eviction(t *testing.T) {
+	d := newTestDiskCache(t, 30)
+	putEntry(t, d, "a", []byte("aaaaaaaaaa"))
+	putEntry(t, d, "b", []byte("bbbbbbbbbb"))
+	putEntry(t, d, "c", []byte("cccccccccc"))
+
+	// a is used again, so b is now the least recently used
+	_, err := d.read("a")
+	testutil.AssertNoError(t, err, "read should succeed")
+	putEntry(t, d, "d", []byte("dddddddddd"))
+
+	testutil.AssertEqual(t, 3, d.Len(), "entries")
+	testutil.AssertEqual(t, int64(30), d.Size(), "size")
+	_, ok := d.lookup("b")
+	testutil.AssertFalse(t, ok, "b should be evicted")
+	for _, id := range []string{"a", "c", "d"} {
+		_, ok := d.lookup(id)
+		testutil.AssertTrue(t, ok, id+" should be kept")
+	}
+
+	entries, err := os.ReadDir(filepath.Join(d.dir, diskCacheBlobDirName))
+	testutil.AssertNoError(t, err, "ReadDir should succeed")
+	testutil.AssertEqual(t, 3, len(entries), "files left after eviction")
+
+	t.Run("too_large", func(t *testing.T) {
+		putEntry(t, d, "huge", make([]byte, 31))
+		_, ok := d.lookup("huge")
+		testutil.AssertFalse(t, ok, "an image over the limit is not cached")
+		testutil.AssertEqual(t, 3, d.Len(), "entries")
+	})
+}
+
+// TestDiskCache_AccessTimes tests that reads don't rewrite the index every time
+func TestDiskCache_AccessTimes(t *testing.T) {
+	d := NewDiskCache(t.TempDir(), 0)
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return clock }
+	putEntry(t, d, "abc", []byte("abc"))
+	saved := func() time.Time {
+		e, ok := NewDiskCache(d.dir, 0).lookup("abc")
+		testutil.AssertTrue(t, ok, "entry should be saved")
+		return e.AccessedAt
+	}
+	read := func() {
+		_, err := d.read("abc")
+		testutil.AssertNoError(t, err, "read should succeed")
+	}
+
+	put := clock
+	clock = clock.Add(time.Second)
+	read()
+	testutil.AssertTrue(t, saved().Equal(put), "a read right after a save leaves the index alone")
+
+	clock = clock.Add(diskCacheSaveInterval)
+	read()
+	testutil.AssertTrue(t, saved().Equal(clock), "a read after the interval saves the index")
+
+	clock = clock.Add(time.Second)
+	read()
+	testutil.AssertNoError(t, d.Flush(), "Flush should succeed")
+	testutil.AssertTrue(t, saved().Equal(clock), "Flush saves what the interval held back")
+}
+
+// TestDiskCache_SharedBlob tests renderings with identical bytes sharing a file
+func TestDiskCache_SharedBlob(t *testing.T) {
+	d := newTestDiskCache(t, 0)
+	data := []byte("same bytes")
+	putEntry(t, d, "a", data)
+	putEntry(t, d, "b", data)
+
+	testutil.AssertEqual(t, int64(len(data)), d.Size(), "shared bytes count once")
+
+	d.mu.Lock()
+	d.remove("a")
+	d.mu.Unlock()
+	got, err := d.read("b")
+	testutil.AssertNoError(t, err, "the file should outlive one of its entries")
+	testutil.AssertEqual(t, string(data), string(got), "cached bytes")
+
+	t.Run("replace_with_same_bytes", func(t *testing.T) {
+		putEntry(t, d, "b", data)
+		_, err := d.read("b")
+		testutil.AssertNoError(t, err, "replacing an entry with the same bytes keeps its file")
+	})
+}
+
+// TestDiskCache_Corruption tests recovering from damaged files
+func TestDiskCache_Corruption(t *testing.T) {
+	t.Run("blob", func(t *testing.T) {
+		d := newTestDiskCache(t, 0)
+		putEntry(t, d, "abc", []byte("original"))
+		e, _ := d.lookup("abc")
+		testutil.AssertNoError(t, os.WriteFile(d.blobPath(e.Blob), []byte("tampered"), 0o644), "WriteFile should succeed")
+
+		_, err := d.read("abc")
+		testutil.AssertTrue(t, errors.Is(err, ErrNotCached), "should be ErrNotCached")
+		testutil.AssertEqual(t, 0, d.Len(), "a damaged entry is dropped")
+	})
+
+	t.Run("index", func(t *testing.T) {
+		dir := t.TempDir()
+		testutil.WriteTestFile(t, dir, diskCacheIndexFileName, []byte("{not json"))
+		d := NewDiskCache(dir, 0)
+		testutil.AssertEqual(t, 0, d.Len(), "a damaged index is ignored")
+		putEntry(t, d, "abc", []byte("fresh"))
+		testutil.AssertEqual(t, 1, NewDiskCache(dir, 0).Len(), "and replaced by the next write")
+	})
+}
+
+// TestDiskCache_Clear tests emptying the cache
+func TestDiskCache_Clear(t *testing.T) {
+	d := newTestDiskCache(t, 0)
+	putEntry(t, d, "a", []byte("aaaa"))
+	putEntry(t, d, "b", []byte("bbbb"))
+
+	testutil.AssertNoError(t, d.Clear(), "Clear should succeed")
+	testutil.AssertEqual(t, 0, d.Len(), "entries")
+	testutil.AssertEqual(t, 0, NewDiskCache(d.dir, 0).Len(), "entries after reopening")
+}
+
+// TestRenderOptions tests the cache key part naming a rendering
+func TestRenderOptions(t *testing.T) {
+	withTags(t, "orange")
+	tests := []struct {
+		name     string
+		url      *CatURL
+		expected string
+	}{
+		{"plain", NewCatURL(), ""},
+		{"id_and_tag_ignored", NewCatURL().WithID("abc"), ""},
+		{"tag_ignored", NewCatURL().WithTag("orange"), ""},
+		{"output_ignored", NewCatURL().AsJSON(), ""},
+		{"says", NewCatURL().WithSays("hi there"), "/says/hi%20there"},
+		{"params", NewCatURL().WithWidth(200).WithCAASImageType(CAASImageTypeSquare), "?type=square&width=200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertEqual(t, tt.expected, renderOptions(tt.url), "render options")
+		})
+	}
+}
+
+// cachingServer serves one cat image with an ETag and Last-Modified,
+// answering conditional requests with 304
+type cachingServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	image        []byte
+	etag         string
+	lastModified string
+	downloads    int
+	revalidated  int
+}
+
+func newCachingServer(t *testing.T, image []byte) *cachingServer {
+	t.Helper()
+	s := &cachingServer{image: image, etag: `"v1"`, lastModified: "Wed, 01 Jan 2025 12:00:00 GMT"}
+	s.Server = testutil.NewMockCatAPI(t, testutil.Routes{"/cat/{id}": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("json") == "true" {
+			testutil.ServeJSON(w, fmt.Sprintf(`{"id":"%s","tags":["orange"],"created_at":"2025-01-01T12:00:00Z","url":"/cat/%s","mimetype":"image/png"}`, r.PathValue("id"), r.PathValue("id")))
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.etag != "" {
+			w.Header().Set("ETag", s.etag)
+		}
+		if s.lastModified != "" {
+			w.Header().Set("Last-Modified", s.lastModified)
+		}
+		inm, ims := r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+		if (inm != "" && inm == s.etag) || (inm == "" && ims != "" && ims == s.lastModified) {
+			s.revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.downloads++
+		testutil.ServeImage(w, "image/png", s.image)
+	}})
+	return s
+}
+
+func (s *cachingServer) counts() (downloads, revalidated int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloads, s.revalidated
+}
+
+// TestClient_DiskCache tests fetching through the disk cache
+func TestClient_DiskCache(t *testing.T) {
+	png := testutil.ValidPNGBytes()
+
+	t.Run("etag", func(t *testing.T) {
+		server := newCachingServer(t, png)
+		c := NewClient().WithBaseURL(server.URL).WithDiskCache(newTestDiskCache(t, 0))
+
+		for range 3 {
+			_, _, err := c.CatByID(context.Background(), "abc")
+			testutil.AssertNoError(t, err, "CatByID should succeed")
+		}
+		downloads, revalidated := server.counts()
+		testutil.AssertEqual(t, 1, downloads, "downloads")
+		testutil.AssertEqual(t, 2, revalidated, "revalidations")
+	})
+
+	t.Run("last_modified", func(t *testing.T) {
+		server := newCachingServer(t, png)
+		server.etag = ""
+		c := NewClient().WithBaseURL(server.URL).WithDiskCache(newTestDiskCache(t, 0))
+
+		for range 2 {
+			_, _, err := c.CatByID(context.Background(), "abc")
+			testutil.AssertNoError(t, err, "CatByID should succeed")
+		}
+		downloads, revalidated := server.counts()
+		testutil.AssertEqual(t, 1, downloads, "downloads")
+		testutil.AssertEqual(t, 1, revalidated, "revalidations")
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		server := newCachingServer(t, png)
+		cache := newTestDiskCache(t, 0)
+		c := NewClient().WithBaseURL(server.URL).WithDiskCache(cache)
+
+		_, _, err := c.CatByID(context.Background(), "abc")
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+
+		gif := testutil.ValidGIFBytes()
+		server.mu.Lock()
+		server.image, server.etag = gif, `"v2"`
+		server.mu.Unlock()
+
+		_, _, err = c.CatByID(context.Background(), "abc")
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+		downloads, _ := server.counts()
+		testutil.AssertEqual(t, 2, downloads, "a changed image is downloaded again")
+
+		e, _ := cache.lookup("abc")
+		testutil.AssertEqual(t, `"v2"`, e.ETag, "validator of the new image")
+		data, err := cache.read("abc")
+		testutil.AssertNoError(t, err, "read should succeed")
+		testutil.AssertEqual(t, string(gif), string(data), "cached bytes")
+	})
+
+	t.Run("renderings_cached_apart", func(t *testing.T) {
+		server := newCachingServer(t, png)
+		cache := newTestDiskCache(t, 0)
+		c := NewClient().WithBaseURL(server.URL).WithDiskCache(cache)
+
+		_, _, err := c.CatByID(context.Background(), "abc")
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+		_, _, err = c.Fetch(context.Background(), NewCatURL().WithID("abc").WithWidth(100))
+		testutil.AssertNoError(t, err, "Fetch should succeed")
+
+		downloads, _ := server.counts()
+		testutil.AssertEqual(t, 2, downloads, "downloads")
+		testutil.AssertEqual(t, 2, cache.Len(), "entries")
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		server := newCachingServer(t, png)
+		cache := newTestDiskCache(t, 0)
+		c := NewClient().WithBaseURL(server.URL).WithDiskCache(cache)
+
+		_, _, err := c.CatByID(context.Background(), "abc")
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+		testutil.AssertNoError(t, os.RemoveAll(filepath.Join(cache.dir, diskCacheBlobDirName)), "RemoveAll should succeed")
+
+		_, _, err = c.CatByID(context.Background(), "abc")
+		testutil.AssertNoError(t, err, "CatByID should download a lost image again")
+		downloads, _ := server.counts()
+		testutil.AssertEqual(t, 2, downloads, "downloads")
+	})
+}
+
+// TestClient_Offline tests serving only cached cats
+func TestClient_Offline(t *testing.T) {
+	withTags(t, "orange", "cute")
+	server := newCachingServer(t, testutil.ValidPNGBytes())
+	cache := newTestDiskCache(t, 0)
+
+	_, _, err := NewClient().WithBaseURL(server.URL).WithDiskCache(cache).CatByID(context.Background(), "abc")
+	testutil.AssertNoError(t, err, "CatByID should succeed")
+	server.Close()
+
+	offline := NewClient().WithBaseURL(server.URL).WithDiskCache(cache).WithOffline(true)
+
+	t.Run("by_id", func(t *testing.T) {
+		img, meta, err := offline.CatByID(context.Background(), "abc")
+		testutil.AssertNoError(t, err, "CatByID should be served from the cache")
+		testutil.AssertNotNil(t, img, "image")
+		testutil.AssertEqual(t, "abc", meta.ID, "id")
+	})
+
+	t.Run("random", func(t *testing.T) {
+		_, meta, err := offline.RandomCat(context.Background(), nil)
+		testutil.AssertNoError(t, err, "RandomCat should be served from the cache")
+		testutil.AssertEqual(t, "abc", meta.ID, "id")
+	})
+
+	t.Run("tagged", func(t *testing.T) {
+		_, meta, err := offline.Fetch(context.Background(), NewCatURL().WithTag("orange"))
+		testutil.AssertNoError(t, err, "a cached cat with the tag should be served")
+		testutil.AssertEqual(t, "abc", meta.ID, "id")
+
+		_, _, err = offline.Fetch(context.Background(), NewCatURL().WithTag("cute"))
+		testutil.AssertTrue(t, errors.Is(err, ErrNotCached), "should be ErrNotCached")
+	})
+
+	t.Run("not_cached", func(t *testing.T) {
+		_, _, err := offline.CatByID(context.Background(), "other")
+		testutil.AssertTrue(t, errors.Is(err, ErrNotCached), "should be ErrNotCached")
+
+		_, _, err = offline.Fetch(context.Background(), NewCatURL().WithSays("hi"))
+		testutil.AssertTrue(t, errors.Is(err, ErrNotCached), "another rendering is not cached")
+	})
+
+	t.Run("no_cache", func(t *testing.T) {
+		_, _, err := NewClient().WithOffline(true).RandomCat(context.Background(), nil)
+		testutil.AssertTrue(t, errors.Is(err, ErrNotCached), "should be ErrNotCached")
+	})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// ProgressFunc is told how much of an image has been downloaded. total is
+// the Content-Length, or -1 when the server didn't send one. It is called
+// from the goroutine doing the fetch, and again from zero if the download
+// is retried.
+type ProgressFunc func(received, total int64)
+
+type progressReader struct {
+	r        io.Reader
+	received int64
+	total    int64
+	report   ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.received += int64(n)
+		p.report(p.received, p.total)
+	}
+	return n, err
+}
+
+// readImageBody streams an image body, giving up as soon as it grows past
+// the MaxBytes limit rather than buffering whatever the server sends
+func (c *Client) readImageBody(resp *http.Response) ([]byte, error) {
+	total := resp.ContentLength
+	if total >= 0 {
+		if err := c.limits.checkBytes(total); err != nil {
+			return nil, err
+		}
+	}
+
+	r := io.Reader(resp.Body)
+	if c.limits.MaxBytes > 0 {
+		// one byte over is enough to tell the body is too large
+		r = io.LimitReader(r, c.limits.MaxBytes+1)
+	}
+	if c.progress != nil {
+		c.progress(0, total)
+		r = &progressReader{r: r, total: total, report: c.progress}
+	}
+
+	var buf bytes.Buffer
+	if total > 0 {
+		buf.Grow(int(total))
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, classifyTransportError(err)
+	}
+	if err := c.limits.checkBytes(int64(buf.Len())); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// progressLog records the calls made to a ProgressFunc
+type progressLog struct {
+	mu    sync.Mutex
+	calls [][2]int64
+}
+
+func (l *progressLog) report(received, total int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, [2]int64{received, total})
+}
+
+func (l *progressLog) last() [2]int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.calls) == 0 {
+		return [2]int64{}
+	}
+	return l.calls[len(l.calls)-1]
+}
+
+// newImageServer serves size bytes of image, optionally without a Content-Length
+func newImageServer(t *testing.T, size int, chunked bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": testutil.Counted(&requests, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		if !chunked {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		chunk := make([]byte, 1024)
+		for sent := 0; sent < size; sent += len(chunk) {
+			w.Write(chunk[:min(len(chunk), size-sent)])
+			if chunked {
+				w.(http.Flusher).Flush()
+			}
+		}
+	})})
+	return server, &requests
+}
+
+// TestClient_Download_Progress tests progress reports while downloading
+func TestClient_Download_Progress(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunked bool
+		total   int64
+	}{
+		{"content_length", false, 10000},
+		{"unknown_length", true, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newImageServer(t, 10000, tt.chunked)
+			var log progressLog
+			c := NewClient().WithProgress(log.report)
+
+			data, err := c.getImageBytes(context.Background(), server.URL)
+			testutil.AssertNoError(t, err, "getImageBytes should succeed")
+			testutil.AssertEqual(t, 10000, len(data), "bytes")
+
+			testutil.AssertTrue(t, len(log.calls) > 2, "progress should be reported more than once")
+			testutil.AssertEqual(t, [2]int64{0, tt.total}, log.calls[0], "first report")
+			testutil.AssertEqual(t, [2]int64{10000, tt.total}, log.last(), "last report")
+			for i := 1; i < len(log.calls); i++ {
+				testutil.AssertTrue(t, log.calls[i][0] > log.calls[i-1][0], "progress should grow")
+			}
+		})
+	}
+}
+
+// TestClient_Download_Limit tests refusing bodies over the MaxBytes limit
+func TestClient_Download_Limit(t *testing.T) {
+	limits := DecodeLimits{MaxBytes: 4096}
+
+	t.Run("content_length", func(t *testing.T) {
+		server, _ := newImageServer(t, 10000, false)
+		var log progressLog
+		c := NewClient().WithDecodeLimits(limits).WithProgress(log.report)
+
+		_, err := c.getImageBytes(context.Background(), server.URL)
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+		testutil.AssertEqual(t, 0, len(log.calls), "nothing should be read")
+	})
+
+	t.Run("unknown_length", func(t *testing.T) {
+		server, requests := newImageServer(t, 1<<20, true)
+		var log progressLog
+		c := NewClient().WithDecodeLimits(limits).WithProgress(log.report).WithRetryPolicy(fastRetryPolicy(3))
+
+		_, err := c.getImageBytes(context.Background(), server.URL)
+		testutil.AssertTrue(t, errors.Is(err, ErrImageTooLarge), "should be ErrImageTooLarge")
+		testutil.AssertTrue(t, log.last()[0] <= limits.MaxBytes+1, "reading should stop at the limit")
+		testutil.AssertEqual(t, int32(1), requests.Load(), "too large is not retried")
+	})
+
+	t.Run("at_limit", func(t *testing.T) {
+		server, _ := newImageServer(t, 4096, true)
+		data, err := NewClient().WithDecodeLimits(limits).getImageBytes(context.Background(), server.URL)
+		testutil.AssertNoError(t, err, "an image at the limit should download")
+		testutil.AssertEqual(t, 4096, len(data), "bytes")
+	})
+
+	t.Run("no_limit", func(t *testing.T) {
+		server, _ := newImageServer(t, 10000, true)
+		data, err := NewClient().WithDecodeLimits(DecodeLimits{}).getImageBytes(context.Background(), server.URL)
+		testutil.AssertNoError(t, err, "getImageBytes should succeed")
+		testutil.AssertEqual(t, 10000, len(data), "bytes")
+	})
+}
+
+// TestClient_Fetch_Progress tests progress reports through Fetch
+func TestClient_Fetch_Progress(t *testing.T) {
+	png := testutil.ValidPNGBytes()
+	server := newStandInServer(t, "image/png", png)
+	var log progressLog
+
+	_, _, err := NewClient().WithBaseURL(server.URL).WithProgress(log.report).RandomCat(context.Background(), NewCatURL())
+	testutil.AssertNoError(t, err, "RandomCat should succeed")
+	testutil.AssertEqual(t, [2]int64{int64(len(png)), int64(len(png))}, log.last(), "last report")
+}
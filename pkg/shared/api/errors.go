@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxErrorBodySnippet caps how much of an error response is kept in HTTPStatusError
+const maxErrorBodySnippet = 256
+
+var (
+	ErrHTTPStatus     = fmt.Errorf("unexpected http status")
+	ErrMetadataDecode = fmt.Errorf("cannot decode cat metadata")
+	ErrImageDecode    = fmt.Errorf("cannot decode cat image")
+	ErrMIMEMismatch   = fmt.Errorf("unexpected content type")
+	ErrTimeout        = fmt.Errorf("request timed out")
+	ErrCatNotFound    = fmt.Errorf("cat not found")
+)
+
+// HTTPStatusError is returned when the server answers with a non-2xx status.
+// It matches ErrHTTPStatus with errors.Is.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string        // the start of the response body
+	RetryAfter time.Duration // parsed Retry-After header, 0 when absent
+}
+
+func (e *HTTPStatusError) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.URL, e.Status)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrHTTPStatus
+}
+
+// checkStatus turns a non-2xx response to a request for rawURL into an
+// *HTTPStatusError. The URL is passed in because a RoundTripper need not
+// set resp.Request.
+func checkStatus(rawURL string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+	body := strings.TrimSpace(strings.ToValidUTF8(string(snippet), string(utf8.RuneError)))
+
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return &HTTPStatusError{
+		URL:        rawURL,
+		StatusCode: resp.StatusCode,
+		Status:     status,
+		Body:       body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// checkImageContentType rejects responses that are clearly not an image, like
+// an HTML error page served with a 200. A missing or generic binary type is
+// left for the decoder to judge.
+func checkImageContentType(resp *http.Response) error {
+	header := resp.Header.Get("Content-Type")
+	if header == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrMIMEMismatch, header)
+	}
+	if strings.HasPrefix(mediaType, "image/") || mediaType == "application/octet-stream" {
+		return nil
+	}
+	return fmt.Errorf("%w: expected an image, got %s", ErrMIMEMismatch, mediaType)
+}
+
+// catNotFound turns a 404 for a request pinned to a cat id into
+// ErrCatNotFound, keeping the HTTPStatusError in the chain
+func catNotFound(u *CatURL, err error) error {
+	var statusErr *HTTPStatusError
+	if u.hasID && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %q: %w", ErrCatNotFound, u.catID, err)
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// classifyTransportError marks deadline and network timeouts with ErrTimeout
+func classifyTransportError(err error) error {
+	if err != nil && isTimeout(err) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// TestClient_Errors_HTTPStatus tests that non-2xx responses become *HTTPStatusError
+func TestClient_Errors_HTTPStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		metaStatus   int
+		imageStatus  int
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "metadata_500",
+			metaStatus:   http.StatusInternalServerError,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "metadata broke",
+		},
+		{
+			name:         "metadata_404",
+			metaStatus:   http.StatusNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: "metadata broke",
+		},
+		{
+			name:         "image_404",
+			imageStatus:  http.StatusNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: "image broke",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("json") == "true" {
+					if tt.metaStatus != 0 {
+						http.Error(w, "metadata broke", tt.metaStatus)
+						return
+					}
+					w.Write([]byte(`{"id":"x","url":"/cat/x","mimetype":"image/png"}`))
+					return
+				}
+				if tt.imageStatus != 0 {
+					http.Error(w, "image broke", tt.imageStatus)
+					return
+				}
+				w.Write(testutil.ValidPNGBytes())
+			}})
+
+			_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).RandomCat(context.Background(), nil)
+
+			var statusErr *HTTPStatusError
+			testutil.AssertTrue(t, errors.As(err, &statusErr), "should be an *HTTPStatusError")
+			testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "should match ErrHTTPStatus")
+			testutil.AssertEqual(t, tt.expectedCode, statusErr.StatusCode, "status code")
+			testutil.AssertEqual(t, tt.expectedBody, statusErr.Body, "body snippet")
+			testutil.AssertFalse(t, errors.Is(err, ErrMetadataDecode), "should not be reported as a decode error")
+		})
+	}
+}
+
+// TestHTTPStatusError_BodySnippet tests that long error bodies are truncated
+func TestHTTPStatusError_BodySnippet(t *testing.T) {
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("x", 4096), http.StatusBadGateway)
+	}})
+
+	_, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).Tags(context.Background())
+
+	var statusErr *HTTPStatusError
+	testutil.AssertTrue(t, errors.As(err, &statusErr), "should be an *HTTPStatusError")
+	testutil.AssertEqual(t, maxErrorBodySnippet, len(statusErr.Body), "body snippet length")
+	testutil.AssertContains(t, statusErr.Error(), "502", "error message mentions the status")
+}
+
+// TestClient_Errors_Decode tests the decode error sentinels
+func TestClient_Errors_Decode(t *testing.T) {
+	tests := []struct {
+		name        string
+		metadata    string
+		imageType   string
+		imageData   []byte
+		expectedErr error
+	}{
+		{
+			name:        "malformed_metadata",
+			metadata:    testutil.MalformedMetadataJSON(),
+			expectedErr: ErrMetadataDecode,
+		},
+		{
+			name:        "empty_metadata",
+			metadata:    "",
+			expectedErr: ErrMetadataDecode,
+		},
+		{
+			name:        "corrupted_image",
+			metadata:    `{"id":"x","url":"/cat/x","mimetype":"image/jpeg"}`,
+			imageType:   "image/jpeg",
+			imageData:   testutil.CorruptedImageBytes(),
+			expectedErr: ErrImageDecode,
+		},
+		{
+			name:        "html_instead_of_image",
+			metadata:    `{"id":"x","url":"/cat/x","mimetype":"image/jpeg"}`,
+			imageType:   "text/html; charset=utf-8",
+			imageData:   []byte("<html>not a cat</html>"),
+			expectedErr: ErrMIMEMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("json") == "true" {
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(tt.metadata))
+					return
+				}
+				w.Header().Set("Content-Type", tt.imageType)
+				w.Write(tt.imageData)
+			}})
+
+			img, meta, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).RandomCat(context.Background(), nil)
+
+			testutil.AssertTrue(t, errors.Is(err, tt.expectedErr), "expected "+tt.expectedErr.Error()+", got "+errString(err))
+			testutil.AssertNil(t, img, "image should be nil on error")
+			testutil.AssertNil(t, meta, "metadata should be nil on error")
+		})
+	}
+}
+
+// TestClient_Errors_Timeout tests that timeouts are reported as ErrTimeout
+func TestClient_Errors_Timeout(t *testing.T) {
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}})
+
+	t.Run("client_timeout", func(t *testing.T) {
+		_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).WithTimeout(50*time.Millisecond).RandomCat(context.Background(), nil)
+		testutil.AssertTrue(t, errors.Is(err, ErrTimeout), "should match ErrTimeout")
+		testutil.AssertContains(t, err.Error(), "deadline", "original error is kept")
+	})
+
+	t.Run("context_deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).RandomCat(ctx, nil)
+		testutil.AssertTrue(t, errors.Is(err, ErrTimeout), "should match ErrTimeout")
+		testutil.AssertTrue(t, errors.Is(err, context.DeadlineExceeded), "should match context.DeadlineExceeded")
+	})
+
+	t.Run("cancel_is_not_timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).RandomCat(ctx, nil)
+		testutil.AssertFalse(t, errors.Is(err, ErrTimeout), "cancellation should not match ErrTimeout")
+	})
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
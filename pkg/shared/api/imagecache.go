@@ -0,0 +1,169 @@
+package api
+
+import (
+	"container/list"
+	"image"
+	"sync"
+)
+
+const DefaultImageCacheSize = 128 << 20
+
+// ImageCache is a bounded in-memory cache of decoded images, so a cat shown
+// before doesn't need decoding again. Entries are keyed by RenderKey and
+// the least recently used are dropped once the decoded pixels outgrow the
+// size limit.
+type ImageCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List // most recently used first
+	items    map[string]*list.Element
+}
+
+type imageCacheItem struct {
+	key   string
+	img   image.Image
+	meta  CatMetadata
+	bytes int64
+}
+
+// NewImageCache creates a cache holding at most maxBytes of decoded pixels,
+// a maxBytes of 0 means no limit
+func NewImageCache(maxBytes int64) *ImageCache {
+	return &ImageCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// RenderKey names one rendering of a cat: the cat id followed by the
+// rendering options of u, if any, e.g. "abc/says/hi?fontSize=30". A nil u
+// is a plain rendering, whose key is just the id.
+func RenderKey(id string, u *CatURL) string {
+	if u == nil {
+		return id
+	}
+	return diskCacheKey(id, renderOptions(u))
+}
+
+// ImageBytes estimates the memory held by the pixels of img, counting every
+// frame of an *Animation
+func ImageBytes(img image.Image) int64 {
+	switch img := img.(type) {
+	case *Animation:
+		var total int64
+		for _, frame := range img.Frames {
+			total += int64(len(frame.Pix))
+		}
+		return total
+	case *image.RGBA:
+		return int64(len(img.Pix))
+	case *image.NRGBA:
+		return int64(len(img.Pix))
+	case *image.Paletted:
+		return int64(len(img.Pix) + 4*len(img.Palette))
+	case *image.Gray:
+		return int64(len(img.Pix))
+	case *image.YCbCr:
+		return int64(len(img.Y) + len(img.Cb) + len(img.Cr))
+	case *image.NYCbCrA:
+		return int64(len(img.Y) + len(img.Cb) + len(img.Cr) + len(img.A))
+	case nil:
+		return 0
+	}
+	b := img.Bounds()
+	return int64(b.Dx()) * int64(b.Dy()) * 4
+}
+
+// Get returns the cached image for key and marks it as recently used
+func (c *ImageCache) Get(key string) (image.Image, *CatMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, nil, false
+	}
+	c.order.MoveToFront(el)
+	item := el.Value.(*imageCacheItem)
+	meta := item.meta
+	return item.img, &meta, true
+}
+
+// Add caches img for key, replacing what was cached under it. An image
+// larger than the whole cache isn't kept.
+func (c *ImageCache) Add(key string, img image.Image, meta *CatMetadata) {
+	item := &imageCacheItem{key: key, img: img, bytes: ImageBytes(img)}
+	if meta != nil {
+		item.meta = *meta
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	if c.maxBytes > 0 && item.bytes > c.maxBytes {
+		return
+	}
+	c.items[key] = c.order.PushFront(item)
+	c.size += item.bytes
+	for c.maxBytes > 0 && c.size > c.maxBytes {
+		c.remove(c.order.Back().Value.(*imageCacheItem).key)
+	}
+}
+
+// Remove drops key from the cache
+func (c *ImageCache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+func (c *ImageCache) remove(key string) {
+	el, ok := c.items[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(el)
+	delete(c.items, key)
+	c.size -= el.Value.(*imageCacheItem).bytes
+}
+
+// Clear empties the cache
+func (c *ImageCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.items)
+	c.size = 0
+}
+
+// Len returns the number of cached images
+func (c *ImageCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Size returns the bytes of decoded pixels in the cache
+func (c *ImageCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// cachedImage looks a rendering up in the client's in-memory cache
+func (c *Client) cachedImage(id, key string) (image.Image, bool) {
+	if c.images == nil || id == "" {
+		return nil, false
+	}
+	img, _, ok := c.images.Get(key)
+	return img, ok
+}
+
+// cacheImage adds a decoded rendering to the client's in-memory cache
+func (c *Client) cacheImage(key string, img image.Image, meta *CatMetadata) {
+	if c.images == nil || meta.ID == "" {
+		return
+	}
+	c.images.Add(key, img, meta)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// rgba is a w x h image using 4*w*h bytes
+func rgba(w, h int) *image.RGBA {
+	return image.NewRGBA(image.Rect(0, 0, w, h))
+}
+
+// boundsOnly is an image type ImageBytes doesn't know
+type boundsOnly struct{ image.Rectangle }
+
+func (b boundsOnly) ColorModel() color.Model { return color.RGBAModel }
+func (b boundsOnly) At(x, y int) color.Color { return color.RGBA{} }
+
+// TestImageBytes tests estimating the memory held by images
+func TestImageBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		img      image.Image
+		expected int64
+	}{
+		{"nil", nil, 0},
+		{"rgba", rgba(4, 3), 48},
+		{"gray", image.NewGray(image.Rect(0, 0, 4, 3)), 12},
+		{"paletted", image.NewPaletted(image.Rect(0, 0, 4, 3), color.Palette{color.Black, color.White}), 12 + 8},
+		{"ycbcr", image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420), 16 + 4 + 4},
+		{"animation", &Animation{Frames: []*image.RGBA{rgba(2, 2), rgba(2, 2), rgba(2, 2)}}, 48},
+		{"unknown", boundsOnly{image.Rect(0, 0, 5, 5)}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertEqual(t, tt.expected, ImageBytes(tt.img), "bytes")
+		})
+	}
+}
+
+// TestImageCache_GetAdd tests storing and finding images
+func TestImageCache_GetAdd(t *testing.T) {
+	c := NewImageCache(0)
+	img := rgba(2, 2)
+	c.Add("abc", img, &CatMetadata{ID: "abc", MIMEType: "image/png"})
+
+	got, meta, ok := c.Get("abc")
+	testutil.AssertTrue(t, ok, "should be cached")
+	testutil.AssertTrue(t, got == image.Image(img), "the same image should be returned")
+	testutil.AssertEqual(t, "image/png", meta.MIMEType, "metadata")
+	testutil.AssertEqual(t, int64(16), c.Size(), "size")
+
+	_, _, ok = c.Get("missing")
+	testutil.AssertFalse(t, ok, "should not be cached")
+
+	// replacing keeps the accounting right
+	c.Add("abc", rgba(1, 1), nil)
+	testutil.AssertEqual(t, 1, c.Len(), "entries")
+	testutil.AssertEqual(t, int64(4), c.Size(), "size after replacing")
+
+	c.Remove("abc")
+	testutil.AssertEqual(t, 0, c.Len(), "entries after Remove")
+	testutil.AssertEqual(t, int64(0), c.Size(), "size after Remove")
+}
+
+// TestImageCache_Eviction tests dropping the least recently used images
+func TestImageCache_Eviction(t *testing.T) {
+	c := NewImageCache(48) // three 2x2 images
+	for _, key := range []string{"a", "b", "c"} {
+		c.Add(key, rgba(2, 2), nil)
+	}
+	c.Get("a") // b is now the least recently used
+	c.Add("d", rgba(2, 2), nil)
+
+	for key, cached := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		_, _, ok := c.Get(key)
+		testutil.AssertEqual(t, cached, ok, key+" cached")
+	}
+	testutil.AssertEqual(t, int64(48), c.Size(), "size")
+
+	t.Run("large_image_evicts_several", func(t *testing.T) {
+		c.Add("e", rgba(4, 2), nil)
+		testutil.AssertEqual(t, 2, c.Len(), "entries")
+		testutil.AssertTrue(t, c.Size() <= 48, "size within the limit")
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		c.Add("huge", rgba(4, 4), nil)
+		_, _, ok := c.Get("huge")
+		testutil.AssertFalse(t, ok, "an image larger than the cache is not kept")
+		testutil.AssertEqual(t, 2, c.Len(), "other entries are kept")
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		c.Clear()
+		testutil.AssertEqual(t, 0, c.Len(), "entries")
+		testutil.AssertEqual(t, int64(0), c.Size(), "size")
+	})
+}
+
+// TestImageCache_Concurrent tests using one cache from many goroutines
+func TestImageCache_Concurrent(t *testing.T) {
+	c := NewImageCache(16 * 10)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				key := string(rune('a' + (i+j)%20))
+				if _, _, ok := c.Get(key); !ok {
+					c.Add(key, rgba(2, 2), nil)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	testutil.AssertTrue(t, c.Size() <= 160, "size within the limit")
+	testutil.AssertEqual(t, int64(c.Len())*16, c.Size(), "size matches the entries")
+}
+
+// TestRenderKey tests naming renderings
+func TestRenderKey(t *testing.T) {
+	testutil.AssertEqual(t, "abc", RenderKey("abc", nil), "nil CatURL")
+	testutil.AssertEqual(t, "abc", RenderKey("abc", NewCatURL()), "plain")
+	testutil.AssertEqual(t, "abc/says/hi?fontSize=30", RenderKey("abc", NewCatURL().WithSays("hi").WithFontSize(30)), "says")
+}
+
+// TestClient_ImageCache tests skipping the download and decode of a cached cat
+func TestClient_ImageCache(t *testing.T) {
+	png := testutil.ValidPNGBytes()
+	var downloads atomic.Int32
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/cat/{id}": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("json") == "true" {
+			testutil.ServeJSON(w, `{"id":"abc","tags":[],"url":"/cat/abc","mimetype":"image/png"}`)
+			return
+		}
+		downloads.Add(1)
+		testutil.ServeImage(w, "image/png", png)
+	}})
+
+	cache := NewImageCache(0)
+	c := NewClient().WithBaseURL(server.URL).WithImageCache(cache)
+
+	first, _, err := c.CatByID(context.Background(), "abc")
+	testutil.AssertNoError(t, err, "CatByID should succeed")
+	second, meta, err := c.CatByID(context.Background(), "abc")
+	testutil.AssertNoError(t, err, "CatByID should succeed")
+
+	testutil.AssertEqual(t, int32(1), downloads.Load(), "downloads")
+	testutil.AssertTrue(t, first == second, "the cached image should be returned")
+	testutil.AssertEqual(t, "abc", meta.ID, "metadata")
+
+	_, _, err = c.Fetch(context.Background(), NewCatURL().WithID("abc").WithWidth(50))
+	testutil.AssertNoError(t, err, "Fetch should succeed")
+	testutil.AssertEqual(t, int32(2), downloads.Load(), "another rendering is downloaded")
+	testutil.AssertEqual(t, 2, cache.Len(), "entries")
+}
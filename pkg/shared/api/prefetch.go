@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"sync"
+	"time"
+)
+
+const DefaultPrefetchSize = 3
+
+var ErrPrefetchStopped = fmt.Errorf("prefetcher stopped")
+
+// prefetchedCat is one queued cat
+type prefetchedCat struct {
+	img  image.Image
+	meta *CatMetadata
+}
+
+// prefetchGeneration is the queue for one set of settings. Changing the
+// settings cancels it and starts a new one, so stale cats are never served.
+// Only cats are queued; a failed fetch is kept as err until the next
+// attempt succeeds, so errors from an outage are never served after it.
+type prefetchGeneration struct {
+	u      *CatURL
+	key    string // u generated, to spot settings that didn't change
+	queue  chan prefetchedCat
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	err    error         // why the latest fetch failed, nil once one succeeds
+	failed chan struct{} // closed and replaced when a fetch fails
+}
+
+// setErr records the outcome of the latest fetch, waking NextCat calls
+// waiting on an empty queue when it failed
+func (g *prefetchGeneration) setErr(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+	if err != nil {
+		close(g.failed)
+		g.failed = make(chan struct{})
+	}
+}
+
+// failure returns a channel closed by the next failed fetch, and the
+// latest fetch error
+func (g *prefetchGeneration) failure() (<-chan struct{}, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.failed, g.err
+}
+
+// Prefetcher keeps a few cats fetched and decoded in the background, so the
+// next one is ready the moment it is asked for. The queue refills as cats
+// are taken from it.
+type Prefetcher struct {
+	client *Client
+	size   int
+	ctx    context.Context
+	stop   context.CancelFunc
+
+	mu  sync.Mutex
+	gen *prefetchGeneration
+	wg  sync.WaitGroup // the fill goroutines of every generation, for Stop
+}
+
+// NewPrefetcher starts fetching cats described by u with c, keeping up to
+// size of them ready. It runs until ctx is done or Stop is called.
+func NewPrefetcher(ctx context.Context, c *Client, u *CatURL, size int) *Prefetcher {
+	if u == nil {
+		u = NewCatURL()
+	}
+	p := &Prefetcher{client: c, size: max(size, 1)}
+	p.ctx, p.stop = context.WithCancel(ctx)
+	p.mu.Lock()
+	p.start(u)
+	p.mu.Unlock()
+	return p
+}
+
+// start replaces the current generation, the caller holds mu
+func (p *Prefetcher) start(u *CatURL) {
+	if p.gen != nil {
+		p.gen.cancel()
+	}
+	key, _ := u.Generate()
+	gen := &prefetchGeneration{u: u, key: key, queue: make(chan prefetchedCat, p.size), failed: make(chan struct{})}
+	gen.ctx, gen.cancel = context.WithCancel(p.ctx)
+	p.gen = gen
+	p.wg.Go(func() { p.fill(gen) })
+}
+
+// fill fetches cats into the generation's queue until it is cancelled. After
+// a failure the error is recorded and the next attempt waits a little longer.
+func (p *Prefetcher) fill(gen *prefetchGeneration) {
+	for failures := 0; ; {
+		img, meta, err := p.client.Fetch(gen.ctx, gen.u)
+		if gen.ctx.Err() != nil {
+			return
+		}
+		gen.setErr(err)
+		if err == nil {
+			failures = 0
+			select {
+			case gen.queue <- prefetchedCat{img: img, meta: meta}:
+			case <-gen.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		failures++
+		wait := p.client.retry.backoff(failures)
+		if wait <= 0 {
+			wait = time.Second
+		}
+		log.Printf("Prefetching failed, trying again in %v: %v", wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-gen.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// SetURL changes the cats being prefetched. Queued cats and the fetch in
+// flight for the old settings are dropped, unless the settings are equal.
+func (p *Prefetcher) SetURL(u *CatURL) {
+	if u == nil {
+		u = NewCatURL()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ctx.Err() != nil {
+		return
+	}
+	if key, err := u.Generate(); err == nil && key == p.gen.key {
+		return
+	}
+	p.start(u)
+}
+
+// URL returns the settings cats are currently prefetched with
+func (p *Prefetcher) URL() *CatURL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.gen.u.clone()
+}
+
+// Ready returns how many cats are queued
+func (p *Prefetcher) Ready() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.gen.queue)
+}
+
+// Next returns the next prefetched cat, waiting for one when none is ready.
+// When none is queued and the latest fetch in the background failed, its
+// error is returned as it is.
+func (p *Prefetcher) Next(ctx context.Context) (image.Image, *CatMetadata, error) {
+	img, meta, _, err := p.NextCat(ctx)
+	return img, meta, err
+}
+
+// NextCat is Next, also returning the settings the cat was fetched with,
+// e.g. for its RenderKey
+func (p *Prefetcher) NextCat(ctx context.Context) (image.Image, *CatMetadata, *CatURL, error) {
+	for {
+		p.mu.Lock()
+		gen := p.gen
+		p.mu.Unlock()
+
+		// a queued cat is served even if later fetches failed
+		select {
+		case cat := <-gen.queue:
+			if gen.ctx.Err() == nil {
+				return cat.img, cat.meta, gen.u.clone(), nil
+			}
+			// fetched with settings that changed since, drop it
+			continue
+		default:
+		}
+		failed, err := gen.failure()
+		if err != nil && gen.ctx.Err() == nil {
+			return nil, nil, gen.u.clone(), err
+		}
+
+		select {
+		case cat := <-gen.queue:
+			if gen.ctx.Err() == nil {
+				return cat.img, cat.meta, gen.u.clone(), nil
+			}
+			// fetched with settings that changed since, drop it
+		case <-failed:
+			// the latest fetch failed, report it
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		case <-gen.ctx.Done():
+			if p.ctx.Err() != nil {
+				return nil, nil, nil, ErrPrefetchStopped
+			}
+			// the settings changed, wait on the new queue
+		}
+	}
+}
+
+// Stop cancels all prefetching and waits for the fetches in flight to
+// return. Next returns ErrPrefetchStopped afterwards.
+func (p *Prefetcher) Stop() {
+	// under mu, so SetURL can't start a generation once Wait has begun
+	p.mu.Lock()
+	p.stop()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// newPrefetchServer serves random cats with ids counting up, prefixed by
+// the tag asked for, e.g. "orange-3". While failing is set every request
+// gets a 500.
+func newPrefetchServer(t *testing.T) (*httptest.Server, *atomic.Bool) {
+	t.Helper()
+	var served atomic.Int32
+	var failing atomic.Bool
+	png := testutil.ValidPNGBytes()
+
+	metadata := func(w http.ResponseWriter, prefix string) {
+		id := fmt.Sprintf("%s-%d", prefix, served.Add(1))
+		testutil.ServeJSON(w, fmt.Sprintf(`{"id":"%s","tags":[],"url":"/cat/%s","mimetype":"image/png"}`, id, id))
+	}
+	server := testutil.NewMockCatAPI(t, testutil.Routes{
+		"/cat": func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				http.Error(w, "down", http.StatusInternalServerError)
+				return
+			}
+			metadata(w, "cat")
+		},
+		"/cat/{idOrTag}": func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				http.Error(w, "down", http.StatusInternalServerError)
+				return
+			}
+			if r.URL.Query().Get("json") == "true" {
+				metadata(w, r.PathValue("idOrTag"))
+				return
+			}
+			testutil.ServeImage(w, "image/png", png)
+		},
+	})
+	return server, &failing
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting: %s", msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestPrefetcher(t *testing.T, server *httptest.Server, u *CatURL, size int) *Prefetcher {
+	t.Helper()
+	c := NewClient().WithBaseURL(server.URL).WithRetryPolicy(fastRetryPolicy(1))
+	p := NewPrefetcher(context.Background(), c, u, size)
+	// cleanups run last in first, so a test calling withTags first gets its
+	// prefetcher stopped, with no fetch still reading the tags, before the
+	// tags are swapped back
+	t.Cleanup(p.Stop)
+	return p
+}
+
+// TestPrefetcher_Fill tests keeping the queue full
+func TestPrefetcher_Fill(t *testing.T) {
+	server, _ := newPrefetchServer(t)
+	p := newTestPrefetcher(t, server, nil, 3)
+
+	waitFor(t, func() bool { return p.Ready() == 3 }, "queue should fill")
+	time.Sleep(20 * time.Millisecond)
+	testutil.AssertEqual(t, 3, p.Ready(), "the queue never grows past its size")
+
+	img, meta, err := p.Next(context.Background())
+	testutil.AssertNoError(t, err, "Next should succeed")
+	testutil.AssertNotNil(t, img, "image")
+	testutil.AssertEqual(t, "cat-1", meta.ID, "cats come in the order they were fetched")
+
+	waitFor(t, func() bool { return p.Ready() == 3 }, "queue should refill")
+}
+
+// TestPrefetcher_SetURL tests dropping cats fetched with old settings
+func TestPrefetcher_SetURL(t *testing.T) {
+	withTags(t, "orange")
+	server, _ := newPrefetchServer(t)
+	p := newTestPrefetcher(t, server, NewCatURL(), 2)
+	waitFor(t, func() bool { return p.Ready() == 2 }, "queue should fill")
+
+	p.SetURL(NewCatURL().WithTag("orange"))
+	for range 4 {
+		_, meta, err := p.Next(context.Background())
+		testutil.AssertNoError(t, err, "Next should succeed")
+		testutil.AssertTrue(t, strings.HasPrefix(meta.ID, "orange-"), "only cats with the new settings, got "+meta.ID)
+	}
+
+	t.Run("same_settings", func(t *testing.T) {
+		waitFor(t, func() bool { return p.Ready() == 2 }, "queue should fill")
+		p.SetURL(NewCatURL().WithTag("orange"))
+		testutil.AssertEqual(t, 2, p.Ready(), "equal settings keep the queue")
+	})
+
+	t.Run("next_cat", func(t *testing.T) {
+		_, meta, u, err := p.NextCat(context.Background())
+		testutil.AssertNoError(t, err, "NextCat should succeed")
+		testutil.AssertTrue(t, strings.HasPrefix(meta.ID, "orange-"), "cat with the current settings, got "+meta.ID)
+		generated, err := u.Generate()
+		testutil.AssertNoError(t, err, "Generate should succeed")
+		testutil.AssertEqual(t, "https://cataas.com/cat/orange", generated, "settings the cat was fetched with")
+	})
+
+	t.Run("url", func(t *testing.T) {
+		generated, err := p.URL().Generate()
+		testutil.AssertNoError(t, err, "Generate should succeed")
+		testutil.AssertEqual(t, "https://cataas.com/cat/orange", generated, "current settings")
+	})
+}
+
+// TestPrefetcher_Errors tests handing fetch errors to Next and recovering
+func TestPrefetcher_Errors(t *testing.T) {
+	server, failing := newPrefetchServer(t)
+	failing.Store(true)
+	p := newTestPrefetcher(t, server, nil, 2)
+
+	_, _, err := p.Next(context.Background())
+	testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "the fetch error should be returned")
+
+	failing.Store(false)
+	waitFor(t, func() bool {
+		_, _, err := p.Next(context.Background())
+		return err == nil
+	}, "prefetching should recover")
+}
+
+// TestPrefetcher_Outage tests that errors from an outage are not served
+// once the server has recovered
+func TestPrefetcher_Outage(t *testing.T) {
+	server, failing := newPrefetchServer(t)
+	failing.Store(true)
+	p := newTestPrefetcher(t, server, nil, 2)
+
+	_, _, err := p.Next(context.Background())
+	testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "the fetch error should be returned")
+	// the outage lasts for many more failed fetches than the queue holds
+	time.Sleep(50 * time.Millisecond)
+	testutil.AssertEqual(t, 0, p.Ready(), "errors are not queued")
+
+	failing.Store(false)
+	waitFor(t, func() bool { return p.Ready() > 0 }, "prefetching should recover")
+	for range 4 {
+		_, meta, err := p.Next(context.Background())
+		testutil.AssertNoError(t, err, "no error from the outage should be served")
+		testutil.AssertNotNil(t, meta, "metadata")
+	}
+}
+
+// TestPrefetcher_Stop tests ending prefetching
+func TestPrefetcher_Stop(t *testing.T) {
+	server, _ := newPrefetchServer(t)
+
+	t.Run("stop", func(t *testing.T) {
+		p := newTestPrefetcher(t, server, nil, 1)
+		p.Stop()
+		_, _, err := p.Next(context.Background())
+		testutil.AssertTrue(t, errors.Is(err, ErrPrefetchStopped), "should be ErrPrefetchStopped")
+	})
+
+	t.Run("parent_context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewPrefetcher(ctx, NewClient().WithBaseURL(server.URL), nil, 1)
+		cancel()
+		_, _, err := p.Next(context.Background())
+		testutil.AssertTrue(t, errors.Is(err, ErrPrefetchStopped), "should be ErrPrefetchStopped")
+	})
+
+	t.Run("next_context", func(t *testing.T) {
+		blocked := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}})
+		p := newTestPrefetcher(t, blocked, nil, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, _, err := p.Next(ctx)
+		testutil.AssertTrue(t, errors.Is(err, context.DeadlineExceeded), "should be the context error")
+	})
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"flag"
+	"net/url"
+	"strconv"
+)
+
+// CatRequest is a plain, serializable description of a cat request. It can
+// be loaded from JSON or YAML presets or filled from command line flags, and
+// converts to and from a CatURL. Nil and empty fields are left out.
+type CatRequest struct {
+	ID       string             `json:"id,omitempty" yaml:"id,omitempty"`
+	Tag      string             `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Says     string             `json:"says,omitempty" yaml:"says,omitempty"`
+	Type     *CAASImageType     `json:"type,omitempty" yaml:"type,omitempty"`
+	Filter   *CAASImageFilter   `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Fit      *CAASImageFit      `json:"fit,omitempty" yaml:"fit,omitempty"`
+	Position *CAASImagePosition `json:"position,omitempty" yaml:"position,omitempty"`
+	Width    *int               `json:"width,omitempty" yaml:"width,omitempty"`
+	Height   *int               `json:"height,omitempty" yaml:"height,omitempty"`
+	Blur     *int               `json:"blur,omitempty" yaml:"blur,omitempty"`
+	RGB      *RGBAdjust         `json:"rgb,omitempty" yaml:"rgb,omitempty"`
+	HSL      *HSLAdjust         `json:"hsl,omitempty" yaml:"hsl,omitempty"`
+	Font     *FontOptions       `json:"font,omitempty" yaml:"font,omitempty"`
+	Output   CAASOutput         `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+// RGBAdjust holds the custom filter's colour channels, each 0-255
+type RGBAdjust struct {
+	R *int `json:"r,omitempty" yaml:"r,omitempty"`
+	G *int `json:"g,omitempty" yaml:"g,omitempty"`
+	B *int `json:"b,omitempty" yaml:"b,omitempty"`
+}
+
+// HSLAdjust holds the custom filter's brightness, saturation, hue and lightness
+type HSLAdjust struct {
+	Brightness *int `json:"brightness,omitempty" yaml:"brightness,omitempty"`
+	Saturation *int `json:"saturation,omitempty" yaml:"saturation,omitempty"`
+	Hue        *int `json:"hue,omitempty" yaml:"hue,omitempty"`
+	Lightness  *int `json:"lightness,omitempty" yaml:"lightness,omitempty"`
+}
+
+// FontOptions styles the text of a Says request
+type FontOptions struct {
+	Font       *CAASFont `json:"font,omitempty" yaml:"font,omitempty"`
+	Size       *int      `json:"size,omitempty" yaml:"size,omitempty"`
+	Color      string    `json:"color,omitempty" yaml:"color,omitempty"`
+	Background string    `json:"background,omitempty" yaml:"background,omitempty"`
+}
+
+// CatURL builds the CatURL described by the request. RGB and HSL
+// adjustments imply the custom filter when Filter is not set. Every invalid
+// option is reported in the returned error.
+func (r CatRequest) CatURL() (*CatURL, error) {
+	u := NewCatURL()
+	if r.ID != "" {
+		u = u.WithID(r.ID)
+	}
+	if r.Tag != "" {
+		u = u.WithTag(r.Tag)
+	}
+	if r.Says != "" {
+		u = u.WithSays(r.Says)
+	}
+
+	if r.Type != nil {
+		u = u.WithCAASImageType(*r.Type)
+	}
+	if r.Filter != nil {
+		u = u.WithCAASImageFilter(*r.Filter)
+	} else if r.RGB != nil || r.HSL != nil {
+		u = u.WithCAASImageFilter(CAASImageFilterCustom)
+	}
+	if r.Fit != nil {
+		u = u.WithCAASImageFit(*r.Fit)
+	}
+	if r.Position != nil {
+		u = u.WithCAASImagePosition(*r.Position)
+	}
+	if r.Width != nil {
+		u = u.WithWidth(*r.Width)
+	}
+	if r.Height != nil {
+		u = u.WithHeight(*r.Height)
+	}
+	if r.Blur != nil {
+		u = u.WithBlur(*r.Blur)
+	}
+
+	if rgb := r.RGB; rgb != nil {
+		if rgb.R != nil {
+			u = u.WithFilterR(*rgb.R)
+		}
+		if rgb.G != nil {
+			u = u.WithFilterG(*rgb.G)
+		}
+		if rgb.B != nil {
+			u = u.WithFilterB(*rgb.B)
+		}
+	}
+	if hsl := r.HSL; hsl != nil {
+		if hsl.Brightness != nil {
+			u = u.WithBrightness(*hsl.Brightness)
+		}
+		if hsl.Saturation != nil {
+			u = u.WithSaturation(*hsl.Saturation)
+		}
+		if hsl.Hue != nil {
+			u = u.WithHue(*hsl.Hue)
+		}
+		if hsl.Lightness != nil {
+			u = u.WithLightness(*hsl.Lightness)
+		}
+	}
+
+	if font := r.Font; font != nil {
+		if font.Font != nil {
+			u = u.WithFont(*font.Font)
+		}
+		if font.Size != nil {
+			u = u.WithFontSize(*font.Size)
+		}
+		if font.Color != "" {
+			u = u.WithFontColor(font.Color)
+		}
+		if font.Background != "" {
+			u = u.WithFontBackground(font.Background)
+		}
+	}
+
+	switch r.Output {
+	case CAASOutputImage:
+	case CAASOutputJSON:
+		u = u.AsJSON()
+	case CAASOutputHTML:
+		u = u.AsHTML()
+	default:
+		u = u.reject("output", r.Output.String(), "not a supported value", ErrInvalidOption)
+	}
+
+	if err := u.Err(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Request describes the CatURL as a CatRequest. The base URL is not part of
+// a request, so it is dropped.
+func (c *CatURL) Request() (CatRequest, error) {
+	if err := c.Err(); err != nil {
+		return CatRequest{}, err
+	}
+
+	r := CatRequest{ID: c.catID, Tag: c.tag, Says: c.saysText}
+	// the values passed Validate, so every lookup and Atoi below succeeds
+	r.Type = enumParam(c.params, caasKeyType, CAASImageTypes)
+	r.Filter = enumParam(c.params, caasKeyFilter, CAASImageFilters)
+	r.Fit = enumParam(c.params, caasKeyFit, CAASImageFits)
+	r.Position = enumParam(c.params, caasKeyPosition, CAASImagePositions)
+	r.Width = intParam(c.params, caasKeyWidth)
+	r.Height = intParam(c.params, caasKeyHeight)
+	r.Blur = intParam(c.params, caasKeyBlur)
+
+	rgb := RGBAdjust{
+		R: intParam(c.params, caasKeyRed),
+		G: intParam(c.params, caasKeyGreen),
+		B: intParam(c.params, caasKeyBlue),
+	}
+	if rgb != (RGBAdjust{}) {
+		r.RGB = &rgb
+	}
+	hsl := HSLAdjust{
+		Brightness: intParam(c.params, caasKeyBrightness),
+		Saturation: intParam(c.params, caasKeySaturation),
+		Hue:        intParam(c.params, caasKeyHue),
+		Lightness:  intParam(c.params, caasKeyLightness),
+	}
+	if hsl != (HSLAdjust{}) {
+		r.HSL = &hsl
+	}
+	font := FontOptions{
+		Font:       enumParam(c.params, caasKeyFont, CAASFonts),
+		Size:       intParam(c.params, caasKeyFontSize),
+		Color:      c.params.Get(caasKeyFontColor),
+		Background: c.params.Get(caasKeyFontBackground),
+	}
+	if font != (FontOptions{}) {
+		r.Font = &font
+	}
+
+	switch {
+	case c.asJSON:
+		r.Output = CAASOutputJSON
+	case c.asHTML:
+		r.Output = CAASOutputHTML
+	}
+	return r, nil
+}
+
+func intParam(params url.Values, key string) *int {
+	if !params.Has(key) {
+		return nil
+	}
+	n, err := strconv.Atoi(params.Get(key))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func enumParam[K ~int](params url.Values, key string, m map[K]string) *K {
+	if !params.Has(key) {
+		return nil
+	}
+	k, ok := lookup(m, params.Get(key))
+	if !ok {
+		return nil
+	}
+	return &k
+}
+
+// RegisterFlags adds a flag for every field of the request to fs. Flags that
+// are not given leave their field unset.
+func (r *CatRequest) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.ID, "id", r.ID, "cat id")
+	fs.StringVar(&r.Tag, "tag", r.Tag, "cat tag")
+	fs.StringVar(&r.Says, "says", r.Says, "text to write on the cat")
+	fs.Func("type", "image type: square, medium, small or xsmall", enumFlag(&r.Type))
+	fs.Func("filter", "image filter: mono, negate or custom", enumFlag(&r.Filter))
+	fs.Func("fit", "image fit: cover, contain, fill, inside or outside", enumFlag(&r.Fit))
+	fs.Func("position", `image position, e.g. "center" or "right top"`, enumFlag(&r.Position))
+	fs.Func("width", "image width in pixels", intFlag(&r.Width))
+	fs.Func("height", "image height in pixels", intFlag(&r.Height))
+	fs.Func("blur", "blur amount", intFlag(&r.Blur))
+
+	rgb := func() *RGBAdjust {
+		if r.RGB == nil {
+			r.RGB = &RGBAdjust{}
+		}
+		return r.RGB
+	}
+	fs.Func("r", "custom filter red channel (0-255)", func(s string) error { return intFlag(&rgb().R)(s) })
+	fs.Func("g", "custom filter green channel (0-255)", func(s string) error { return intFlag(&rgb().G)(s) })
+	fs.Func("b", "custom filter blue channel (0-255)", func(s string) error { return intFlag(&rgb().B)(s) })
+
+	hsl := func() *HSLAdjust {
+		if r.HSL == nil {
+			r.HSL = &HSLAdjust{}
+		}
+		return r.HSL
+	}
+	fs.Func("brightness", "custom filter brightness", func(s string) error { return intFlag(&hsl().Brightness)(s) })
+	fs.Func("saturation", "custom filter saturation", func(s string) error { return intFlag(&hsl().Saturation)(s) })
+	fs.Func("hue", "custom filter hue rotation", func(s string) error { return intFlag(&hsl().Hue)(s) })
+	fs.Func("lightness", "custom filter lightness", func(s string) error { return intFlag(&hsl().Lightness)(s) })
+
+	font := func() *FontOptions {
+		if r.Font == nil {
+			r.Font = &FontOptions{}
+		}
+		return r.Font
+	}
+	fs.Func("font", `font for the text, e.g. "Impact"`, func(s string) error { return enumFlag(&font().Font)(s) })
+	fs.Func("font-size", "font size", func(s string) error { return intFlag(&font().Size)(s) })
+	fs.Func("font-color", "font color as hex, e.g. #ffffff", func(s string) error { font().Color = s; return nil })
+	fs.Func("font-background", "text background as hex", func(s string) error { font().Background = s; return nil })
+	fs.TextVar(&r.Output, "output", r.Output, "output: image, json or html")
+}
+
+func intFlag(p **int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = &n
+		return nil
+	}
+}
+
+// enumFlag parses a flag through the enum's UnmarshalText
+func enumFlag[K any, PK interface {
+	*K
+	UnmarshalText([]byte) error
+}](p **K) func(string) error {
+	return func(s string) error {
+		v := new(K)
+		if err := PK(v).UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+	"gopkg.in/yaml.v3"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// TestCatRequest_CatURL tests conversion of a request into a URL
+func TestCatRequest_CatURL(t *testing.T) {
+	withTags(t, "orange")
+
+	tests := []struct {
+		name     string
+		req      CatRequest
+		expected string
+	}{
+		{"empty", CatRequest{}, "https://cataas.com/cat"},
+		{"tag_says", CatRequest{Tag: "orange", Says: "hi"}, "https://cataas.com/cat/orange/says/hi"},
+		{"basic", CatRequest{
+			ID:       "abc",
+			Type:     ptr(CAASImageTypeSmall),
+			Fit:      ptr(CAASImageFitFill),
+			Position: ptr(CAASImagePositionLeftTop),
+			Width:    ptr(10),
+			Height:   ptr(20),
+			Blur:     ptr(0),
+		}, "https://cataas.com/cat/abc?blur=0&fit=fill&height=20&position=left+top&type=small&width=10"},
+		{"rgb_implies_custom", CatRequest{RGB: &RGBAdjust{R: ptr(1)}, HSL: &HSLAdjust{Hue: ptr(-90)}},
+			"https://cataas.com/cat?filter=custom&hue=-90&r=1"},
+		{"font", CatRequest{Says: "hi", Font: &FontOptions{Font: ptr(CAASFontArialBlack), Size: ptr(30), Color: "#fff"}},
+			"https://cataas.com/cat/says/hi?font=Arial+Black&fontColor=%23fff&fontSize=30"},
+		{"json", CatRequest{Output: CAASOutputJSON}, "https://cataas.com/cat?json=true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := tt.req.CatURL()
+			testutil.AssertNoError(t, err, "CatURL")
+			got, err := u.Generate()
+			testutil.AssertNoError(t, err, "Generate")
+			testutil.AssertEqual(t, tt.expected, got, "url")
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		u, err := CatRequest{
+			Tag:    "dog",
+			Filter: ptr(CAASImageFilterMono),
+			RGB:    &RGBAdjust{R: ptr(300)},
+			Font:   &FontOptions{Size: ptr(10)},
+			Output: CAASOutput(9),
+		}.CatURL()
+		testutil.AssertNil(t, u, "no CatURL on error")
+		for _, e := range []error{ErrInvalidTag, ErrInvalidOption, ErrFontNoSays} {
+			testutil.AssertTrue(t, errors.Is(err, e), "should report "+e.Error())
+		}
+	})
+}
+
+// TestCatRequest_RoundTrip tests CatURL -> CatRequest -> JSON -> CatRequest -> CatURL
+func TestCatRequest_RoundTrip(t *testing.T) {
+	withTags(t, "orange")
+
+	u := NewCatURL().WithTag("orange").WithSays("hello there").
+		WithCAASImageType(CAASImageTypeMedium).
+		WithCAASImageFilter(CAASImageFilterCustom).WithFilterRGB(1, 2, 3).WithLightness(4).
+		WithCAASImagePosition(CAASImagePositionRightBottom).WithWidth(100).
+		WithFont(CAASFontTimesNewRoman).WithFontSize(12).WithFontBackground("#000000").
+		AsHTML()
+	expected, err := u.Generate()
+	testutil.AssertNoError(t, err, "Generate")
+
+	req, err := u.Request()
+	testutil.AssertNoError(t, err, "Request")
+
+	data, err := json.Marshal(req)
+	testutil.AssertNoError(t, err, "Marshal")
+
+	var decoded CatRequest
+	testutil.AssertNoError(t, json.Unmarshal(data, &decoded), "Unmarshal")
+
+	back, err := decoded.CatURL()
+	testutil.AssertNoError(t, err, "CatURL")
+	got, err := back.Generate()
+	testutil.AssertNoError(t, err, "Generate after round trip")
+	testutil.AssertEqual(t, expected, got, "round trip via "+string(data))
+}
+
+// TestCatRequest_JSON tests the preset format
+func TestCatRequest_JSON(t *testing.T) {
+	t.Run("readable_enums", func(t *testing.T) {
+		data, err := json.Marshal(CatRequest{Position: ptr(CAASImagePositionRightTop), Output: CAASOutputJSON})
+		testutil.AssertNoError(t, err, "Marshal")
+		testutil.AssertEqual(t, `{"position":"right top","output":"json"}`, string(data), "json")
+	})
+
+	t.Run("preset", func(t *testing.T) {
+		preset := `{"says":"meow","filter":"negate","width":200,"font":{"font":"Comic Sans MS","size":40}}`
+		var req CatRequest
+		testutil.AssertNoError(t, json.Unmarshal([]byte(preset), &req), "Unmarshal")
+		testutil.AssertEqual(t, CAASImageFilterNegate, *req.Filter, "filter")
+		testutil.AssertEqual(t, CAASFontComicSansMS, *req.Font.Font, "font")
+		testutil.AssertEqual(t, 200, *req.Width, "width")
+	})
+
+	t.Run("unknown_enum", func(t *testing.T) {
+		var req CatRequest
+		err := json.Unmarshal([]byte(`{"fit":"stretch"}`), &req)
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidOption), "should be ErrInvalidOption")
+	})
+
+	t.Run("invalid_url", func(t *testing.T) {
+		_, err := NewCatURL().WithFontSize(10).Request()
+		testutil.AssertTrue(t, errors.Is(err, ErrFontNoSays), "should be ErrFontNoSays")
+	})
+}
+
+// TestCatRequest_YAML tests YAML presets, which use the same field names and
+// enum spellings as JSON
+func TestCatRequest_YAML(t *testing.T) {
+	t.Run("round_trip", func(t *testing.T) {
+		withTags(t, "orange")
+
+		u := NewCatURL().WithTag("orange").WithSays("hello there").
+			WithCAASImageFilter(CAASImageFilterCustom).WithFilterRGB(1, 2, 3).WithHue(4).
+			WithCAASImageFit(CAASImageFitCover).WithHeight(300).
+			WithFont(CAASFontComicSansMS).WithFontColor("#ff00ff").
+			AsJSON()
+		expected, err := u.Generate()
+		testutil.AssertNoError(t, err, "Generate")
+
+		req, err := u.Request()
+		testutil.AssertNoError(t, err, "Request")
+
+		data, err := yaml.Marshal(req)
+		testutil.AssertNoError(t, err, "Marshal")
+
+		var decoded CatRequest
+		testutil.AssertNoError(t, yaml.Unmarshal(data, &decoded), "Unmarshal")
+
+		back, err := decoded.CatURL()
+		testutil.AssertNoError(t, err, "CatURL")
+		got, err := back.Generate()
+		testutil.AssertNoError(t, err, "Generate after round trip")
+		testutil.AssertEqual(t, expected, got, "round trip via "+string(data))
+	})
+
+	t.Run("readable_enums", func(t *testing.T) {
+		data, err := yaml.Marshal(CatRequest{Position: ptr(CAASImagePositionRightTop), Output: CAASOutputJSON})
+		testutil.AssertNoError(t, err, "Marshal")
+		testutil.AssertEqual(t, "position: right top\noutput: json\n", string(data), "yaml")
+	})
+
+	t.Run("preset", func(t *testing.T) {
+		preset := "says: meow\nfilter: negate\nwidth: 200\nfont:\n  font: Comic Sans MS\n  size: 40\n"
+		var req CatRequest
+		testutil.AssertNoError(t, yaml.Unmarshal([]byte(preset), &req), "Unmarshal")
+		testutil.AssertEqual(t, CAASImageFilterNegate, *req.Filter, "filter")
+		testutil.AssertEqual(t, CAASFontComicSansMS, *req.Font.Font, "font")
+		testutil.AssertEqual(t, 40, *req.Font.Size, "font size")
+		testutil.AssertEqual(t, 200, *req.Width, "width")
+	})
+
+	t.Run("unknown_enum", func(t *testing.T) {
+		var req CatRequest
+		err := yaml.Unmarshal([]byte("fit: stretch\n"), &req)
+		testutil.AssertTrue(t, errors.Is(err, ErrInvalidOption), "should be ErrInvalidOption")
+	})
+}
+
+// TestCatRequest_RegisterFlags tests building a request from command line flags
+func TestCatRequest_RegisterFlags(t *testing.T) {
+	var req CatRequest
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	req.RegisterFlags(fs)
+
+	err := fs.Parse([]string{
+		"-says", "hi", "-type", "xsmall", "-position", "right top",
+		"-width", "50", "-g", "20", "-hue", "30",
+		"-font", "Georgia", "-font-color", "#abcdef", "-output", "json",
+	})
+	testutil.AssertNoError(t, err, "Parse")
+
+	testutil.AssertEqual(t, "hi", req.Says, "says")
+	testutil.AssertEqual(t, CAASImageTypeXSmall, *req.Type, "type")
+	testutil.AssertEqual(t, CAASImagePositionRightTop, *req.Position, "position")
+	testutil.AssertEqual(t, 50, *req.Width, "width")
+	testutil.AssertNil(t, req.RGB.R, "unset channel")
+	testutil.AssertEqual(t, 20, *req.RGB.G, "green")
+	testutil.AssertEqual(t, 30, *req.HSL.Hue, "hue")
+	testutil.AssertEqual(t, CAASFontGeorgia, *req.Font.Font, "font")
+	testutil.AssertEqual(t, "#abcdef", req.Font.Color, "font color")
+	testutil.AssertEqual(t, CAASOutputJSON, req.Output, "output")
+	testutil.AssertNil(t, req.Filter, "filter not given")
+
+	t.Run("bad_value", func(t *testing.T) {
+		var req CatRequest
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		req.RegisterFlags(fs)
+		testutil.AssertError(t, fs.Parse([]string{"-filter", "sepia"}), "unknown filter")
+		testutil.AssertError(t, fs.Parse([]string{"-width", "wide"}), "non-numeric width")
+	})
+}
+
+// TestEnumText tests the text form of the enums
+func TestEnumText(t *testing.T) {
+	testutil.AssertEqual(t, "right top", CAASImagePositionRightTop.String(), "String")
+	testutil.AssertEqual(t, "api.CAASImageFit(42)", CAASImageFit(42).String(), "unknown String")
+
+	_, err := CAASFont(42).MarshalText()
+	testutil.AssertTrue(t, errors.Is(err, ErrInvalidOption), "unknown value should not marshal")
+
+	for typ, name := range CAASImageTypes {
+		var got CAASImageType
+		testutil.AssertNoError(t, got.UnmarshalText([]byte(name)), "UnmarshalText "+name)
+		testutil.AssertEqual(t, typ, got, name)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Only transient
+// failures are retried: timeouts, dropped or refused connections and the
+// status codes listed in RetryableStatus.
+type RetryPolicy struct {
+	MaxAttempts     int           // total attempts including the first, values below 2 disable retries
+	BaseDelay       time.Duration // delay before the first retry, doubled for each further retry
+	MaxDelay        time.Duration // upper bound for a single backoff delay, 0 means unbounded; a server's Retry-After is not capped
+	MaxElapsed      time.Duration // deadline shared by all attempts of a request, and by a Fetch, 0 means none
+	Jitter          float64       // fraction (0-1) of each delay that is randomised
+	RetryableStatus []int
+}
+
+// NoRetry makes a single attempt per request
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// DefaultRetryPolicy is the policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		MaxElapsed:  30 * time.Second,
+		Jitter:      0.2,
+		RetryableStatus: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// backoff returns the delay before the given retry, starting at 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	if jitter > 0 && delay > 0 {
+		delay -= time.Duration(jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// delay picks the wait before the given retry, preferring the server's
+// Retry-After. That is waited out in full, since retrying sooner is what the
+// server asked us not to do; only the MaxElapsed deadline bounds it.
+func (p RetryPolicy) delay(retry int, err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+	return p.backoff(retry)
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(p.RetryableStatus, statusErr.StatusCode)
+	}
+
+	// every *url.Error is a net.Error, so only some of them are transient;
+	// a DNS failure or an unsupported scheme won't go away by retrying
+	if errors.Is(err, ErrTimeout) || isTimeout(err) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	return slices.ContainsFunc(connectionErrors, func(target error) bool {
+		return errors.Is(err, target)
+	})
+}
+
+// withDeadline bounds ctx by MaxElapsed
+func (p RetryPolicy) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.MaxElapsed <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, p.MaxElapsed)
+}
+
+// retry runs op until it succeeds, fails permanently, runs out of attempts
+// or ctx is done. All attempts share one MaxElapsed deadline, and no retry
+// is started that couldn't begin before it.
+func (p RetryPolicy) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	ctx, cancel := p.withDeadline(ctx)
+	defer cancel()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op(ctx)
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !p.retryable(err) {
+			return err
+		}
+
+		wait := p.delay(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return err
+		}
+		log.Printf("Attempt %d/%d failed, retrying in %v: %v", attempt, attempts, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// parseRetryAfter understands both forms of the Retry-After header
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
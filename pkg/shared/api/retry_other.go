@@ -0,0 +1,11 @@
+//go:build !windows
+
+package api
+
+import "syscall"
+
+// connectionErrors are the socket errors worth retrying
+var connectionErrors = []error{
+	syscall.ECONNRESET,
+	syscall.ECONNREFUSED,
+}
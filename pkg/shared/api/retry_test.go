@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+// fastRetryPolicy keeps retry tests quick
+func fastRetryPolicy(attempts int) RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.MaxAttempts = attempts
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 10 * time.Millisecond
+	return p
+}
+
+// newFlakyServer fails the first failures requests of each kind with status, then serves a cat
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+
+	var metaCalls, imageCalls atomic.Int32
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		calls := &imageCalls
+		if r.URL.Query().Get("json") == "true" || r.URL.Path == caasTagsPath {
+			calls = &metaCalls
+		}
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			http.Error(w, "try again", status)
+			return
+		}
+		switch {
+		case r.URL.Path == caasTagsPath:
+			testutil.ServeJSON(w, `["cute"]`)
+		case r.URL.Query().Get("json") == "true":
+			testutil.ServeJSON(w, `{"id":"flaky","url":"/cat/flaky","mimetype":"image/png"}`)
+		default:
+			testutil.ServeImage(w, "image/png", testutil.ValidPNGBytes())
+		}
+	}})
+	return server, &metaCalls, &imageCalls
+}
+
+// TestClient_Retry_RecoversFromFailures tests that transient failures are retried for every call
+func TestClient_Retry_RecoversFromFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		status   int
+	}{
+		{name: "single_503", failures: 1, status: http.StatusServiceUnavailable},
+		{name: "two_500s", failures: 2, status: http.StatusInternalServerError},
+		{name: "rate_limited", failures: 1, status: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, metaCalls, imageCalls := newFlakyServer(t, tt.failures, tt.status, nil)
+			c := NewClient().WithBaseURL(server.URL).WithRetryPolicy(fastRetryPolicy(3))
+
+			img, _, err := c.RandomCat(context.Background(), nil)
+			testutil.AssertNoError(t, err, "RandomCat should recover")
+			testutil.AssertNotNil(t, img, "image should not be nil")
+			testutil.AssertEqual(t, tt.failures+1, metaCalls.Load(), "metadata attempts")
+			testutil.AssertEqual(t, tt.failures+1, imageCalls.Load(), "image attempts")
+		})
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		server, calls, _ := newFlakyServer(t, 2, http.StatusBadGateway, nil)
+		tags, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(fastRetryPolicy(3)).Tags(context.Background())
+		testutil.AssertNoError(t, err, "Tags should recover")
+		testutil.AssertEqual(t, 1, len(tags), "tags length")
+		testutil.AssertEqual(t, int32(3), calls.Load(), "tag attempts")
+	})
+}
+
+// TestClient_Retry_GivesUp tests that the last error is returned once attempts run out
+func TestClient_Retry_GivesUp(t *testing.T) {
+	server, metaCalls, _ := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(fastRetryPolicy(3)).RandomCat(context.Background(), nil)
+
+	var statusErr *HTTPStatusError
+	testutil.AssertTrue(t, errors.As(err, &statusErr), "should return the last status error")
+	testutil.AssertEqual(t, http.StatusServiceUnavailable, statusErr.StatusCode, "status code")
+	testutil.AssertEqual(t, int32(3), metaCalls.Load(), "metadata attempts")
+}
+
+// TestClient_Retry_PermanentFailure tests that non-retryable statuses fail immediately
+func TestClient_Retry_PermanentFailure(t *testing.T) {
+	server, metaCalls, _ := newFlakyServer(t, 10, http.StatusNotFound, nil)
+
+	_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(fastRetryPolicy(5)).RandomCat(context.Background(), nil)
+
+	testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "should fail with a status error")
+	testutil.AssertEqual(t, int32(1), metaCalls.Load(), "404 should not be retried")
+}
+
+// TestClient_Retry_NoRetry tests that NoRetry makes a single attempt
+func TestClient_Retry_NoRetry(t *testing.T) {
+	server, metaCalls, _ := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+
+	_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(NoRetry).RandomCat(context.Background(), nil)
+
+	testutil.AssertError(t, err, "should fail without retrying")
+	testutil.AssertEqual(t, int32(1), metaCalls.Load(), "metadata attempts")
+}
+
+// TestClient_Retry_HonorsRetryAfter tests that the Retry-After header overrides the backoff
+func TestClient_Retry_HonorsRetryAfter(t *testing.T) {
+	server, _, _ := newFlakyServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"1"}})
+
+	// MaxDelay bounds the backoff only, never the wait the server asked for
+	p := fastRetryPolicy(2)
+
+	start := time.Now()
+	_, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(p).Tags(context.Background())
+
+	testutil.AssertNoError(t, err, "Tags should recover")
+	testutil.AssertTrue(t, time.Since(start) >= time.Second, "should wait for Retry-After")
+}
+
+// TestClient_Retry_RetryAfterPastDeadline tests that a Retry-After beyond
+// MaxElapsed fails at once instead of retrying early
+func TestClient_Retry_RetryAfterPastDeadline(t *testing.T) {
+	server, metaCalls, _ := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}})
+
+	p := fastRetryPolicy(3)
+	p.MaxElapsed = 5 * time.Second
+
+	start := time.Now()
+	_, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(p).Tags(context.Background())
+
+	var statusErr *HTTPStatusError
+	testutil.AssertTrue(t, errors.As(err, &statusErr), "should return the status error")
+	testutil.AssertEqual(t, http.StatusTooManyRequests, statusErr.StatusCode, "status")
+	testutil.AssertTrue(t, time.Since(start) < 2*time.Second, "should not wait for a retry past the deadline")
+	testutil.AssertEqual(t, int32(1), metaCalls.Load(), "attempts")
+}
+
+// TestClient_Retry_ContextCancelledDuringBackoff tests that waiting stops when the context is done
+func TestClient_Retry_ContextCancelledDuringBackoff(t *testing.T) {
+	server, metaCalls, _ := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	p := DefaultRetryPolicy()
+	p.BaseDelay = time.Minute
+	p.MaxDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(p).Tags(ctx)
+
+	testutil.AssertError(t, err, "should fail")
+	testutil.AssertTrue(t, time.Since(start) < 10*time.Second, "should not wait out the backoff")
+	testutil.AssertEqual(t, int32(1), metaCalls.Load(), "attempts")
+}
+
+// TestClient_Retry_MaxElapsed tests that all attempts share one deadline
+func TestClient_Retry_MaxElapsed(t *testing.T) {
+	t.Run("hung_server", func(t *testing.T) {
+		var calls atomic.Int32
+		server := testutil.NewMockCatAPI(t, testutil.Routes{"/": testutil.Counted(&calls, func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})})
+
+		p := fastRetryPolicy(5)
+		p.MaxElapsed = 100 * time.Millisecond
+		start := time.Now()
+		_, _, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(p).RandomCat(context.Background(), nil)
+
+		testutil.AssertTrue(t, errors.Is(err, ErrTimeout), "should time out")
+		testutil.AssertTrue(t, time.Since(start) < 2*time.Second, "attempts should not each get their own deadline")
+		testutil.AssertEqual(t, int32(1), calls.Load(), "no retry once the deadline has passed")
+	})
+
+	t.Run("backoff_past_deadline", func(t *testing.T) {
+		server, metaCalls, _ := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+
+		p := fastRetryPolicy(5)
+		p.BaseDelay = time.Minute
+		p.MaxDelay = time.Minute
+		p.MaxElapsed = 200 * time.Millisecond
+		start := time.Now()
+		_, err := NewClient().WithBaseURL(server.URL).WithRetryPolicy(p).Tags(context.Background())
+
+		testutil.AssertTrue(t, errors.Is(err, ErrHTTPStatus), "should return the last failure")
+		testutil.AssertTrue(t, time.Since(start) < 2*time.Second, "should not wait for a retry past the deadline")
+		testutil.AssertEqual(t, int32(1), metaCalls.Load(), "attempts")
+	})
+}
+
+// TestRetryPolicy_Retryable tests which failures are worth another attempt
+func TestRetryPolicy_Retryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+	dial := func(errno error) error {
+		return &url.Error{Op: "Get", URL: "https://cataas.com/cat", Err: &net.OpError{
+			Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno),
+		}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded), true},
+		{"net timeout", &url.Error{Op: "Get", URL: "https://cataas.com/cat", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, true},
+		{"connection reset", dial(connectionErrors[0]), true},
+		{"connection refused", dial(connectionErrors[1]), true},
+		{"dropped connection", io.ErrUnexpectedEOF, true},
+		{"retryable status", &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"permanent status", &HTTPStatusError{StatusCode: http.StatusNotFound}, false},
+		{"dns failure", &url.Error{Op: "Get", URL: "https://cataas.invalid/cat", Err: &net.DNSError{Err: "no such host", Name: "cataas.invalid", IsNotFound: true}}, false},
+		{"unsupported scheme", &url.Error{Op: "Get", URL: "ftp://cataas.com/cat", Err: errors.New("unsupported protocol scheme \"ftp\"")}, false},
+		{"cancelled", &url.Error{Op: "Get", URL: "https://cataas.com/cat", Err: context.Canceled}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertEqual(t, tt.want, p.retryable(tt.err), "retryable")
+		})
+	}
+}
+
+// TestRetryPolicy_Backoff tests exponential growth, the cap and jitter bounds
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	testutil.AssertEqual(t, 100*time.Millisecond, p.backoff(1), "first retry")
+	testutil.AssertEqual(t, 200*time.Millisecond, p.backoff(2), "second retry")
+	testutil.AssertEqual(t, 400*time.Millisecond, p.backoff(3), "third retry")
+	testutil.AssertEqual(t, time.Second, p.backoff(5), "capped at MaxDelay")
+	testutil.AssertEqual(t, time.Second, p.backoff(100), "large retry counts stay capped")
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		testutil.AssertTrue(t, d >= 100*time.Millisecond && d <= 200*time.Millisecond, "jittered delay within bounds")
+	}
+}
+
+// TestParseRetryAfter tests both Retry-After formats
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		header   string
+		expected time.Duration
+	}{
+		{name: "empty", header: "", expected: 0},
+		{name: "seconds", header: "3", expected: 3 * time.Second},
+		{name: "negative_seconds", header: "-3", expected: 0},
+		{name: "http_date", header: now.Add(5 * time.Second).Format(http.TimeFormat), expected: 5 * time.Second},
+		{name: "past_date", header: now.Add(-time.Hour).Format(http.TimeFormat), expected: 0},
+		{name: "garbage", header: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertEqual(t, tt.expected, parseRetryAfter(tt.header, now), "retry after")
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package api
+
+import "syscall"
+
+// connectionErrors are the socket errors worth retrying. Windows reports
+// its own WSA codes, and syscall has no name for WSAECONNREFUSED.
+var connectionErrors = []error{
+	syscall.WSAECONNRESET,
+	syscall.Errno(10061), // WSAECONNREFUSED
+}
@@ -1,8 +1,6 @@
 package api
 
-import (
-	"net/url"
-)
+import "fmt"
 
 type CAASImageType int
 
@@ -69,13 +67,13 @@ const (
 var CAASImagePositions = map[CAASImagePosition]string{
 	CAASImagePositionCenter:      "center",
 	CAASImagePositionTop:         "top",
-	CAASImagePositionRightTop:    url.QueryEscape("right top"), // right%20top
+	CAASImagePositionRightTop:    "right top",
 	CAASImagePositionRight:       "right",
-	CAASImagePositionRightBottom: url.QueryEscape("right bottom"), // right%20bottom
+	CAASImagePositionRightBottom: "right bottom",
 	CAASImagePositionBottom:      "bottom",
-	CAASImagePositionLeftBottom:  url.QueryEscape("left bottom"), // left%20top
+	CAASImagePositionLeftBottom:  "left bottom",
 	CAASImagePositionLeft:        "left",
-	CAASImagePositionLeftTop:     url.QueryEscape("left top"), // left%20top
+	CAASImagePositionLeftTop:     "left top",
 }
 
 type CAASFont int
@@ -99,11 +97,89 @@ var CAASFonts = map[CAASFont]string{
 	CAASFontAndale:        "Andale",
 	CAASFontMono:          "Mono",
 	CAASFontArial:         "Arial",
-	CAASFontArialBlack:    url.QueryEscape("Arial Black"),
-	CAASFontComicSansMS:   url.QueryEscape("Comic Sans MS"),
-	CAASFontCourierNew:    url.QueryEscape("Courier New"),
+	CAASFontArialBlack:    "Arial Black",
+	CAASFontComicSansMS:   "Comic Sans MS",
+	CAASFontCourierNew:    "Courier New",
 	CAASFontGeorgia:       "Georgia",
-	CAASFontTimesNewRoman: url.QueryEscape("Times New Roman"),
+	CAASFontTimesNewRoman: "Times New Roman",
 	CAASFontVerdana:       "Verdana",
 	CAASFontWebdings:      "Webdings",
 }
+
+// CAASOutput selects what the server returns for a cat
+type CAASOutput int
+
+const (
+	CAASOutputImage CAASOutput = iota
+	CAASOutputJSON
+	CAASOutputHTML
+)
+
+var CAASOutputs = map[CAASOutput]string{
+	CAASOutputImage: "image",
+	CAASOutputJSON:  "json",
+	CAASOutputHTML:  "html",
+}
+
+// The enums marshal to the same strings the server uses, so they read
+// naturally in JSON and YAML presets and can back command line flags.
+
+func enumString[K ~int](m map[K]string, v K) string {
+	if s, ok := m[v]; ok {
+		return s
+	}
+	return fmt.Sprintf("%T(%d)", v, int(v))
+}
+
+func marshalEnum[K ~int](m map[K]string, v K) ([]byte, error) {
+	s, ok := m[v]
+	if !ok {
+		return nil, fmt.Errorf("%w: %T(%d)", ErrInvalidOption, v, int(v))
+	}
+	return []byte(s), nil
+}
+
+func unmarshalEnum[K ~int](m map[K]string, text []byte, v *K) error {
+	k, ok := lookup(m, string(text))
+	if !ok {
+		return fmt.Errorf("%w: %T %q", ErrInvalidOption, *v, text)
+	}
+	*v = k
+	return nil
+}
+
+func (t CAASImageType) String() string               { return enumString(CAASImageTypes, t) }
+func (t CAASImageType) MarshalText() ([]byte, error) { return marshalEnum(CAASImageTypes, t) }
+func (t *CAASImageType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(CAASImageTypes, text, t)
+}
+
+func (f CAASImageFilter) String() string               { return enumString(CAASImageFilters, f) }
+func (f CAASImageFilter) MarshalText() ([]byte, error) { return marshalEnum(CAASImageFilters, f) }
+func (f *CAASImageFilter) UnmarshalText(text []byte) error {
+	return unmarshalEnum(CAASImageFilters, text, f)
+}
+
+func (f CAASImageFit) String() string               { return enumString(CAASImageFits, f) }
+func (f CAASImageFit) MarshalText() ([]byte, error) { return marshalEnum(CAASImageFits, f) }
+func (f *CAASImageFit) UnmarshalText(text []byte) error {
+	return unmarshalEnum(CAASImageFits, text, f)
+}
+
+func (p CAASImagePosition) String() string               { return enumString(CAASImagePositions, p) }
+func (p CAASImagePosition) MarshalText() ([]byte, error) { return marshalEnum(CAASImagePositions, p) }
+func (p *CAASImagePosition) UnmarshalText(text []byte) error {
+	return unmarshalEnum(CAASImagePositions, text, p)
+}
+
+func (f CAASFont) String() string               { return enumString(CAASFonts, f) }
+func (f CAASFont) MarshalText() ([]byte, error) { return marshalEnum(CAASFonts, f) }
+func (f *CAASFont) UnmarshalText(text []byte) error {
+	return unmarshalEnum(CAASFonts, text, f)
+}
+
+func (o CAASOutput) String() string               { return enumString(CAASOutputs, o) }
+func (o CAASOutput) MarshalText() ([]byte, error) { return marshalEnum(CAASOutputs, o) }
+func (o *CAASOutput) UnmarshalText(text []byte) error {
+	return unmarshalEnum(CAASOutputs, text, o)
+}
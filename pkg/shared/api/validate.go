@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/g4s8/hexcolor"
+)
+
+var (
+	ErrEmptyID              = fmt.Errorf("empty cat id")
+	ErrInvalidOption        = fmt.Errorf("invalid option")
+	ErrInvalidColor         = fmt.Errorf("invalid hex color")
+	ErrCustomFilterRequired = fmt.Errorf("option requires the custom filter")
+	ErrFontNoSays           = fmt.Errorf("font options require a Says text")
+)
+
+// customFilterKeys only take effect with filter=custom
+var customFilterKeys = []string{
+	caasKeyRed, caasKeyGreen, caasKeyBlue,
+	caasKeyBrightness, caasKeySaturation, caasKeyHue, caasKeyLightness,
+}
+
+// fontKeys only take effect on a Says URL
+var fontKeys = []string{caasKeyFont, caasKeyFontSize, caasKeyFontColor, caasKeyFontBackground}
+
+// ValidationError describes one problem with a CatURL. It unwraps to one of
+// the package's sentinel errors so callers can use errors.Is.
+type ValidationError struct {
+	Field  string
+	Value  string
+	Reason string
+	Err    error
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s=%q: %s", e.Field, e.Value, e.Reason)
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ParseHexColor parses a colour the way the font options take it, e.g.
+// "#ff00ff" or "#fff". Anything else, the empty string included, is
+// ErrInvalidColor.
+func ParseHexColor(hexColor string) (color.NRGBA, error) {
+	// hexcolor.Parse indexes the string before checking its length
+	if hexColor == "" {
+		return color.NRGBA{}, fmt.Errorf("%w: empty", ErrInvalidColor)
+	}
+	c, err := hexcolor.Parse(hexColor)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("%w: %q", ErrInvalidColor, hexColor)
+	}
+	return color.NRGBA(c), nil
+}
+
+func validHexColor(hexColor string) bool {
+	_, err := ParseHexColor(hexColor)
+	return err == nil
+}
+
+func containsValue[K comparable](m map[K]string, value string) bool {
+	for _, v := range m {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinProblems(problems []ValidationError) error {
+	errs := make([]error, len(problems))
+	for i, problem := range problems {
+		errs[i] = problem
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every problem with the CatURL at once, in a stable order,
+// starting with the options the builder methods rejected. A nil result means
+// Generate will succeed.
+func (c *CatURL) Validate() []ValidationError {
+	problems := slices.Clone(c.rejected)
+	report := func(field, value, reason string, err error) {
+		problems = append(problems, ValidationError{Field: field, Value: value, Reason: reason, Err: err})
+	}
+
+	// path segments
+	if c.hasID && c.hasTag {
+		report("id", c.catID, "cannot be combined with tag "+strconv.Quote(c.tag), ErrIDAndTag)
+	}
+	if c.hasID && c.catID == "" {
+		report("id", c.catID, "must not be empty", ErrEmptyID)
+	}
+	if c.hasTag && c.tag == "" {
+		report("tag", c.tag, "must not be empty", ErrInvalidTag)
+	} else if c.hasTag && AvailableTags.Len() > 0 && !AvailableTags.Contains(c.tag) {
+		// tags can only be checked once the registry has loaded
+		report("tag", c.tag, "not a known cataas tag", ErrInvalidTag)
+	}
+	if c.hasSays && c.saysText == "" {
+		report("says", c.saysText, "must not be empty", ErrSaysNoText)
+	}
+
+	// output format
+	if c.asHTML && c.asJSON {
+		report(caasKeyHTML, "true", "cannot be combined with json", ErrHTMLAndJSON)
+	}
+
+	// enumerated params
+	enums := []struct {
+		key   string
+		valid func(string) bool
+	}{
+		{caasKeyType, func(v string) bool { return containsValue(CAASImageTypes, v) }},
+		{caasKeyFilter, func(v string) bool { return containsValue(CAASImageFilters, v) }},
+		{caasKeyFit, func(v string) bool { return containsValue(CAASImageFits, v) }},
+		{caasKeyPosition, func(v string) bool { return containsValue(CAASImagePositions, v) }},
+		{caasKeyFont, func(v string) bool { return containsValue(CAASFonts, v) }},
+	}
+	for _, enum := range enums {
+		if c.params.Has(enum.key) && !enum.valid(c.params.Get(enum.key)) {
+			report(enum.key, c.params.Get(enum.key), "not a supported value", ErrInvalidOption)
+		}
+	}
+
+	// numeric params
+	numbers := []struct {
+		key      string
+		min, max int
+		reason   string
+	}{
+		{caasKeyWidth, 1, -1, "must be a positive integer"},
+		{caasKeyHeight, 1, -1, "must be a positive integer"},
+		{caasKeyBlur, 0, -1, "must be zero or a positive integer"},
+		{caasKeyRed, 0, 255, "must be between 0 and 255"},
+		{caasKeyGreen, 0, 255, "must be between 0 and 255"},
+		{caasKeyBlue, 0, 255, "must be between 0 and 255"},
+		{caasKeyBrightness, 0, -1, "must be zero or a positive integer"},
+		{caasKeySaturation, 0, -1, "must be zero or a positive integer"},
+		{caasKeyHue, math.MinInt, -1, "must be an integer"},
+		{caasKeyLightness, 0, -1, "must be zero or a positive integer"},
+		{caasKeyFontSize, 1, -1, "must be a positive integer"},
+	}
+	for _, number := range numbers {
+		if !c.params.Has(number.key) {
+			continue
+		}
+		value := c.params.Get(number.key)
+		n, err := strconv.Atoi(value)
+		if err != nil || n < number.min || (number.max >= 0 && n > number.max) {
+			report(number.key, value, number.reason, ErrInvalidOption)
+		}
+	}
+
+	// colors
+	for _, key := range []string{caasKeyFontColor, caasKeyFontBackground} {
+		if c.params.Has(key) && !validHexColor(c.params.Get(key)) {
+			report(key, c.params.Get(key), "must be a hex color like #ff00ff", ErrInvalidColor)
+		}
+	}
+
+	// options that depend on other options
+	if c.params.Get(caasKeyFilter) != CAASImageFilters[CAASImageFilterCustom] {
+		for _, key := range customFilterKeys {
+			if c.params.Has(key) {
+				report(key, c.params.Get(key), "only applies with filter=custom", ErrCustomFilterRequired)
+			}
+		}
+	}
+	if !c.hasSays {
+		for _, key := range fontKeys {
+			if c.params.Has(key) {
+				report(key, c.params.Get(key), "only applies to a Says URL", ErrFontNoSays)
+			}
+		}
+	}
+
+	return problems
+}
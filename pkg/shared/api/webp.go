@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// x/image/webp decodes still images only, so animated files are taken apart
+// here and each frame is handed to it as a still image of its own.
+// https://developers.google.com/speed/webp/docs/riff_container
+
+var ErrWebPFormat = fmt.Errorf("invalid webp")
+
+const (
+	webpAnimationFlag = 1 << 1
+	webpAlphaFlag     = 1 << 4
+
+	webpBlendFlag   = 1 << 1 // set: draw the frame over the canvas without blending
+	webpDisposeFlag = 1 << 0 // set: clear the frame's area once it has been shown
+)
+
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// readRIFFChunks splits the body of a RIFF container into its chunks
+func readRIFFChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%w: truncated chunk header", ErrWebPFormat)
+		}
+		id := string(data[:4])
+		size := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+		if uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: %s chunk overruns the file", ErrWebPFormat, id)
+		}
+		chunks = append(chunks, riffChunk{id: id, data: data[:size]})
+		// chunks are padded to an even size
+		data = data[min(int(size)+int(size&1), len(data)):]
+	}
+	return chunks, nil
+}
+
+// webpChunks checks the RIFF WEBP header and returns the top level chunks
+func webpChunks(data []byte) ([]riffChunk, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("%w: missing RIFF WEBP header", ErrWebPFormat)
+	}
+	size := binary.LittleEndian.Uint32(data[4:8])
+	body := data[12:]
+	if uint64(size) >= 4 && uint64(size)-4 < uint64(len(body)) {
+		body = body[:size-4]
+	}
+	return readRIFFChunks(body)
+}
+
+func writeRIFFChunk(buf *bytes.Buffer, id string, data []byte) {
+	buf.WriteString(id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// decodeWebP decodes a still or animated WebP. An animation with more than
+// one frame is returned as an *Animation.
+func decodeWebP(data []byte, limits DecodeLimits) (image.Image, error) {
+	chunks, err := webpChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].id != "VP8X" || len(chunks[0].data) < 10 || chunks[0].data[0]&webpAnimationFlag == 0 {
+		return webp.Decode(bytes.NewReader(data))
+	}
+
+	frames := 0
+	for _, chunk := range chunks {
+		if chunk.id == "ANMF" {
+			frames++
+		}
+	}
+	if err := limits.checkFrames(webpCanvas(chunks[0].data), frames); err != nil {
+		return nil, err
+	}
+
+	anim, err := newWebPAnimation(chunks)
+	if err != nil {
+		return nil, err
+	}
+	if anim.FrameCount() == 1 {
+		return anim.Frames[0], nil
+	}
+	return anim, nil
+}
+
+// webpCanvas reads the canvas size from a VP8X chunk
+func webpCanvas(vp8x []byte) image.Rectangle {
+	return image.Rect(0, 0, uint24(vp8x[4:7])+1, uint24(vp8x[7:10])+1)
+}
+
+func newWebPAnimation(chunks []riffChunk) (*Animation, error) {
+	canvas := image.NewRGBA(webpCanvas(chunks[0].data))
+
+	a := &Animation{}
+	for _, chunk := range chunks[1:] {
+		switch chunk.id {
+		case "ANIM":
+			if len(chunk.data) < 6 {
+				return nil, fmt.Errorf("%w: short ANIM chunk", ErrWebPFormat)
+			}
+			// WebP counts plays, 0 meaning forever; Animation follows gif.GIF
+			switch loops := int(binary.LittleEndian.Uint16(chunk.data[4:6])); loops {
+			case 0:
+				a.LoopCount = 0
+			case 1:
+				a.LoopCount = -1
+			default:
+				a.LoopCount = loops - 1
+			}
+
+		case "ANMF":
+			if len(chunk.data) < 16 {
+				return nil, fmt.Errorf("%w: short ANMF chunk", ErrWebPFormat)
+			}
+			f := chunk.data
+			x, y := uint24(f[0:3])*2, uint24(f[3:6])*2
+			w, h := uint24(f[6:9])+1, uint24(f[9:12])+1
+			duration := time.Duration(uint24(f[12:15])) * time.Millisecond
+			flags := f[15]
+
+			// the canvas and frame count have been checked against the
+			// limits, so a frame may only be decoded if it lies on the canvas
+			area := image.Rect(x, y, x+w, y+h)
+			if !area.In(canvas.Bounds()) {
+				return nil, fmt.Errorf("%w: frame %d at %v lies outside the %dx%d canvas",
+					ErrWebPFormat, len(a.Frames), area, canvas.Bounds().Dx(), canvas.Bounds().Dy())
+			}
+
+			frame, err := decodeWebPFrame(f[16:], w, h)
+			if err != nil {
+				return nil, fmt.Errorf("frame %d: %w", len(a.Frames), err)
+			}
+
+			op := draw.Over
+			if flags&webpBlendFlag != 0 {
+				op = draw.Src
+			}
+			draw.Draw(canvas, area, frame, frame.Bounds().Min, op)
+
+			disposal := byte(gif.DisposalNone)
+			if flags&webpDisposeFlag != 0 {
+				disposal = gif.DisposalBackground
+			}
+			a.Frames = append(a.Frames, cloneRGBA(canvas))
+			a.Delays = append(a.Delays, duration)
+			a.Disposals = append(a.Disposals, disposal)
+
+			if disposal == gif.DisposalBackground {
+				draw.Draw(canvas, area, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+	if len(a.Frames) == 0 {
+		return nil, fmt.Errorf("%w: animation has no frames", ErrWebPFormat)
+	}
+	return a, nil
+}
+
+// decodeWebPFrame rewraps the chunks of one ANMF frame as a still WebP. The
+// bitstream must be the w x h the frame header declares, as it is sized by
+// that rather than by the header when decoded.
+func decodeWebPFrame(data []byte, w, h int) (image.Image, error) {
+	chunks, err := readRIFFChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWebPFrameSize(chunks, w, h); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, chunk := range chunks {
+		if chunk.id == "ALPH" {
+			// alpha only survives in the extended format
+			vp8x := make([]byte, 10)
+			vp8x[0] = webpAlphaFlag
+			vp8x[4], vp8x[5], vp8x[6] = byte(w-1), byte((w-1)>>8), byte((w-1)>>16)
+			vp8x[7], vp8x[8], vp8x[9] = byte(h-1), byte((h-1)>>8), byte((h-1)>>16)
+			writeRIFFChunk(&body, "VP8X", vp8x)
+			break
+		}
+	}
+	for _, chunk := range chunks {
+		switch chunk.id {
+		case "ALPH", "VP8 ", "VP8L":
+			writeRIFFChunk(&body, chunk.id, chunk.data)
+		}
+	}
+
+	var still bytes.Buffer
+	writeRIFFChunk(&still, "RIFF", body.Bytes())
+	return webp.Decode(&still)
+}
+
+// checkWebPFrameSize reads the size of a frame's bitstream without decoding it
+func checkWebPFrameSize(chunks []riffChunk, w, h int) error {
+	for _, chunk := range chunks {
+		if chunk.id != "VP8 " && chunk.id != "VP8L" {
+			continue
+		}
+		var body bytes.Buffer
+		body.WriteString("WEBP")
+		writeRIFFChunk(&body, chunk.id, chunk.data)
+		var still bytes.Buffer
+		writeRIFFChunk(&still, "RIFF", body.Bytes())
+
+		cfg, err := webp.DecodeConfig(&still)
+		if err != nil {
+			return err
+		}
+		if cfg.Width != w || cfg.Height != h {
+			return fmt.Errorf("%w: %dx%d bitstream in a %dx%d frame", ErrWebPFormat, cfg.Width, cfg.Height, w, h)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: frame has no image data", ErrWebPFormat)
+}
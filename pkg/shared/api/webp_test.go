@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+)
+
+var (
+	webpRed   = color.NRGBA{R: 255, A: 255}
+	webpGreen = color.NRGBA{G: 255, A: 255}
+	webpBlue  = color.NRGBA{B: 255, A: 255}
+)
+
+// TestDecodeWebP_Still tests decoding a still WebP
+func TestDecodeWebP_Still(t *testing.T) {
+	img, err := decodeWebP(testutil.WebPBytes(3, 2, color.NRGBA{R: 10, G: 20, B: 30, A: 255}), DecodeLimits{})
+	testutil.AssertNoError(t, err, "decodeWebP should succeed")
+
+	_, animated := img.(*Animation)
+	testutil.AssertFalse(t, animated, "a still image is not an animation")
+	testutil.AssertImageDimensions(t, img, 3, 2)
+	r, g, b, _ := img.At(1, 1).RGBA()
+	testutil.AssertEqual(t, [3]uint32{10, 20, 30}, [3]uint32{r >> 8, g >> 8, b >> 8}, "colour")
+}
+
+// TestDecodeWebP_Animated tests decoding and compositing an animated WebP
+func TestDecodeWebP_Animated(t *testing.T) {
+	data := testutil.AnimatedWebPBytes(4, 4, 0,
+		testutil.WebPFrame{Width: 4, Height: 4, Color: webpRed, Duration: 50},
+		testutil.WebPFrame{X: 2, Y: 2, Width: 2, Height: 2, Color: webpGreen, Duration: 70, Dispose: true},
+		testutil.WebPFrame{Width: 2, Height: 2, Color: webpBlue, Duration: 90},
+	)
+
+	img, err := decodeWebP(data, DecodeLimits{})
+	testutil.AssertNoError(t, err, "decodeWebP should succeed")
+	anim, ok := img.(*Animation)
+	testutil.AssertTrue(t, ok, "should be an *Animation")
+
+	testutil.AssertEqual(t, 3, anim.FrameCount(), "frame count")
+	testutil.AssertImageDimensions(t, anim, 4, 4)
+	testutil.AssertEqual(t, 70*time.Millisecond, anim.FrameDelay(1), "delay")
+	testutil.AssertEqual(t, 0, anim.PlayCount(), "loops forever")
+
+	testutil.AssertEqual(t, color.Color(color.RGBA{R: 255, A: 255}), anim.Frames[1].At(0, 0), "first frame kept under the second")
+	testutil.AssertEqual(t, color.Color(color.RGBA{G: 255, A: 255}), anim.Frames[1].At(3, 3), "second frame drawn at its offset")
+	testutil.AssertEqual(t, byte(gif.DisposalBackground), anim.Disposals[1], "disposal")
+	testutil.AssertEqual(t, color.Color(color.RGBA{}), anim.Frames[2].At(3, 3), "disposed area cleared")
+	testutil.AssertEqual(t, color.Color(color.RGBA{B: 255, A: 255}), anim.Frames[2].At(0, 0), "third frame drawn")
+}
+
+// TestDecodeWebP_Blending tests alpha blending against replacing the canvas
+func TestDecodeWebP_Blending(t *testing.T) {
+	clear := color.NRGBA{}
+	for _, tt := range []struct {
+		name     string
+		noBlend  bool
+		expected color.RGBA
+	}{
+		{"blend", false, color.RGBA{R: 255, A: 255}},
+		{"no_blend", true, color.RGBA{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testutil.AnimatedWebPBytes(2, 2, 0,
+				testutil.WebPFrame{Width: 2, Height: 2, Color: webpRed, Duration: 50},
+				testutil.WebPFrame{Width: 2, Height: 2, Color: clear, Duration: 50, NoBlend: tt.noBlend},
+			)
+			img, err := decodeWebP(data, DecodeLimits{})
+			testutil.AssertNoError(t, err, "decodeWebP should succeed")
+			testutil.AssertEqual(t, color.Color(tt.expected), img.(*Animation).Frames[1].At(0, 0), "pixel under a transparent frame")
+		})
+	}
+}
+
+// TestDecodeWebP_Loops tests the conversion of the WebP play count
+func TestDecodeWebP_Loops(t *testing.T) {
+	frames := []testutil.WebPFrame{
+		{Width: 1, Height: 1, Color: webpRed, Duration: 10},
+		{Width: 1, Height: 1, Color: webpBlue, Duration: 10},
+	}
+	for loops, plays := range map[int]int{0: 0, 1: 1, 3: 3} {
+		img, err := decodeWebP(testutil.AnimatedWebPBytes(1, 1, loops, frames...), DecodeLimits{})
+		testutil.AssertNoError(t, err, "decodeWebP should succeed")
+		testutil.AssertEqual(t, plays, img.(*Animation).PlayCount(), "play count")
+	}
+
+	t.Run("single_frame", func(t *testing.T) {
+		img, err := decodeWebP(testutil.AnimatedWebPBytes(1, 1, 0, frames[0]), DecodeLimits{})
+		testutil.AssertNoError(t, err, "decodeWebP should succeed")
+		_, animated := img.(*Animation)
+		testutil.AssertFalse(t, animated, "a single frame is a plain image")
+	})
+}
+
+// TestDecodeWebP_Errors tests malformed files
+func TestDecodeWebP_Errors(t *testing.T) {
+	valid := testutil.AnimatedWebPBytes(2, 2, 0,
+		testutil.WebPFrame{Width: 2, Height: 2, Color: webpRed, Duration: 50},
+		testutil.WebPFrame{Width: 2, Height: 2, Color: webpBlue, Duration: 50},
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not_riff", []byte("GIF89a")},
+		{"truncated", valid[:len(valid)-10]},
+		{"no_frames", testutil.AnimatedWebPBytes(2, 2, 0)},
+		{"short_anmf", append(append([]byte{}, valid[:12]...), []byte("ANMF\x04\x00\x00\x00abcd")...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			testutil.AssertNoPanic(t, func() { _, err = decodeWebP(tt.data, DecodeLimits{}) }, "decodeWebP should not panic")
+			testutil.AssertError(t, err, "decodeWebP should fail")
+		})
+	}
+
+	t.Run("error_kind", func(t *testing.T) {
+		_, err := decodeWebP(testutil.AnimatedWebPBytes(2, 2, 0), DecodeLimits{})
+		testutil.AssertTrue(t, errors.Is(err, ErrWebPFormat), "should be ErrWebPFormat")
+	})
+}
+
+// TestDecodeWebP_OversizedFrames tests that frames larger than the canvas
+// are rejected before they are decoded
+func TestDecodeWebP_OversizedFrames(t *testing.T) {
+	limits := DecodeLimits{MaxPixels: 1 << 20}
+	huge := testutil.WebPFrame{Width: 6000, Height: 6000, Color: webpRed, Duration: 50}
+
+	t.Run("frame_outside_canvas", func(t *testing.T) {
+		data := testutil.AnimatedWebPBytes(2, 2, 0, huge, huge)
+		_, err := decodeWebP(data, limits)
+		testutil.AssertTrue(t, errors.Is(err, ErrWebPFormat), "should be ErrWebPFormat")
+	})
+
+	t.Run("bitstream_larger_than_frame", func(t *testing.T) {
+		data := testutil.AnimatedWebPBytes(2, 2, 0, huge, huge)
+		// shrink both frame headers to 1x1, leaving the 6000x6000 bitstreams;
+		// each ANMF payload holds x, y, width-1 and height-1 as 24 bit values
+		for i := 0; i < len(data)-8; i++ {
+			if string(data[i:i+4]) == "ANMF" {
+				copy(data[i+8+6:i+8+12], make([]byte, 6))
+			}
+		}
+		_, err := decodeWebP(data, limits)
+		testutil.AssertTrue(t, errors.Is(err, ErrWebPFormat), "should be ErrWebPFormat")
+	})
+}
+
+// TestClient_Fetch_WebP tests fetching still and animated WebP cats
+func TestClient_Fetch_WebP(t *testing.T) {
+	t.Run("still", func(t *testing.T) {
+		server := newStandInServer(t, "image/webp", testutil.WebPBytes(5, 4, webpGreen))
+		img, meta, err := NewClient().WithBaseURL(server.URL).CatByID(context.Background(), "still")
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+		testutil.AssertEqual(t, "image/webp", meta.GetMIMEType(), "MIME type")
+		testutil.AssertImageDimensions(t, img, 5, 4)
+	})
+
+	t.Run("animated", func(t *testing.T) {
+		server := newStandInServer(t, "image/webp", testutil.AnimatedWebPBytes(2, 2, 0,
+			testutil.WebPFrame{Width: 2, Height: 2, Color: webpRed, Duration: 50},
+			testutil.WebPFrame{Width: 2, Height: 2, Color: webpBlue, Duration: 50},
+		))
+		img, _, err := NewClient().WithBaseURL(server.URL).CatByID(context.Background(), "animated")
+		testutil.AssertNoError(t, err, "CatByID should succeed")
+		_, ok := img.(*Animation)
+		testutil.AssertTrue(t, ok, "should be an *Animation")
+	})
+}
@@ -3,22 +3,56 @@ package catpic
 import (
 	"image"
 	"sync"
+	"time"
 
 	"gioui.org/layout"
+	"gioui.org/op"
 	"gioui.org/op/paint"
+	"gioui.org/unit"
 	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
 )
 
+// Animated is an image with several frames, like *api.Animation. CatPic
+// plays it while drawing.
+type Animated interface {
+	image.Image
+	FrameCount() int
+	Frame(i int) image.Image
+	FrameDelay(i int) time.Duration
+	PlayCount() int // 0 plays forever
+}
+
 type CatPic struct {
 	img       image.Image
+	meta      *api.CatMetadata // of the cat shown, nil when not known
 	mu        sync.Mutex
 	isLoading bool
+
+	// download progress while loading, total is -1 when unknown
+	received int64
+	total    int64
+
+	// animation state, advanced by the frame times passed to Draw
+	anim       Animated
+	frame      int
+	frameStart time.Time
+	plays      int
+	paused     bool
+	pausedAt   time.Time
+
+	// one op per frame so the same texture isn't uploaded again every frame
+	ops []paint.ImageOp
+
+	// decoded images shared with the fetch layer, nil for none
+	cache *api.ImageCache
 }
 
 func NewCatImage(img image.Image) *CatPic {
-	return &CatPic{
-		img: img,
-	}
+	p := &CatPic{}
+	p.setImage(img)
+	return p
 }
 
 func (p *CatPic) IsLoading() bool {
@@ -36,13 +70,58 @@ func (p *CatPic) GetImage() image.Image {
 func (p *CatPic) SetImage(img image.Image) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.setImage(img)
+	p.meta = nil
+}
+
+// Metadata returns the metadata of the cat shown, nil when it was set
+// without any
+func (p *CatPic) Metadata() *api.CatMetadata {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.meta
+}
+
+// setImage swaps the image and restarts playback. A paused CatPic stays
+// paused for the new image.
+func (p *CatPic) setImage(img image.Image) {
 	p.img = img
+	p.anim = nil
+	if anim, ok := img.(Animated); ok && anim.FrameCount() > 1 {
+		p.anim = anim
+	}
+	p.frame = 0
+	p.frameStart = time.Time{}
+	p.plays = 0
+	p.pausedAt = time.Time{}
+	p.ops = nil
+}
+
+// SetCache shares a decoded image cache with the fetch layer, which finds
+// cats shown before in it instead of decoding them again
+func (p *CatPic) SetCache(cache *api.ImageCache) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache = cache
+}
+
+// SetCat shows the image of the cat rendering named key (see api.RenderKey)
+// with its metadata, and adds them to the cache
+func (p *CatPic) SetCat(key string, img image.Image, meta *api.CatMetadata) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cache != nil && key != "" {
+		p.cache.Add(key, img, meta)
+	}
+	p.setImage(img)
+	p.meta = meta
 }
 
 func (p *CatPic) SetLoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.isLoading = true
+	p.received, p.total = 0, -1
 }
 
 func (p *CatPic) ClearLoading() {
@@ -51,15 +130,206 @@ func (p *CatPic) ClearLoading() {
 	p.isLoading = false
 }
 
+// SetProgress records how much of the image being loaded has arrived, it
+// matches api.ProgressFunc
+func (p *CatPic) SetProgress(received, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.received, p.total = received, total
+}
+
+// Progress returns the bytes received of the image being loaded, and the
+// total or -1 when the size isn't known
+func (p *CatPic) Progress() (received, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.received, p.total
+}
+
+// LoadingProgress returns the fraction of the image being loaded that has
+// arrived, and false when nothing is loading or its size isn't known
+func (p *CatPic) LoadingProgress() (float32, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.isLoading || p.total <= 0 {
+		return 0, false
+	}
+	return min(float32(p.received)/float32(p.total), 1), true
+}
+
+// IsAnimated reports whether the current image has more than one frame
+func (p *CatPic) IsAnimated() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.anim != nil
+}
+
+// Frame returns the index of the frame currently shown
+func (p *CatPic) Frame() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.frame
+}
+
+func (p *CatPic) IsPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Pause freezes the animation on the current frame
+func (p *CatPic) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+}
+
+// Resume continues a paused animation where it stopped, or replays one that
+// has finished all its loops
+func (p *CatPic) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = false
+	if p.finished() {
+		p.frame = 0
+		p.frameStart = time.Time{}
+		p.plays = 0
+	}
+}
+
+// TogglePause pauses a playing animation and resumes a paused one
+func (p *CatPic) TogglePause() {
+	if p.IsPaused() {
+		p.Resume()
+	} else {
+		p.Pause()
+	}
+}
+
+func (p *CatPic) finished() bool {
+	if p.anim == nil {
+		return false
+	}
+	playCount := p.anim.PlayCount()
+	return playCount > 0 && p.plays >= playCount
+}
+
+// advance moves the animation forward to now
+func (p *CatPic) advance(now time.Time) {
+	if p.anim == nil || p.finished() {
+		return
+	}
+	if p.paused {
+		if p.pausedAt.IsZero() {
+			p.pausedAt = now
+		}
+		return
+	}
+	if !p.pausedAt.IsZero() {
+		// don't count the time spent paused
+		p.frameStart = p.frameStart.Add(now.Sub(p.pausedAt))
+		p.pausedAt = time.Time{}
+	}
+	if p.frameStart.IsZero() {
+		p.frameStart = now
+		return
+	}
+
+	frames := p.anim.FrameCount()
+	for skipped := 0; ; skipped++ {
+		if skipped > frames {
+			// far behind, e.g. after the window was hidden; restart the frame
+			p.frameStart = now
+			return
+		}
+		end := p.frameStart.Add(p.anim.FrameDelay(p.frame))
+		if now.Before(end) {
+			return
+		}
+		p.frameStart = end
+		if p.frame+1 < frames {
+			p.frame++
+			continue
+		}
+		p.plays++
+		if p.finished() {
+			// stay on the last frame
+			return
+		}
+		p.frame = 0
+	}
+}
+
+// nextFrameAt is when the next frame is due, if the animation is playing
+func (p *CatPic) nextFrameAt() (time.Time, bool) {
+	if p.anim == nil || p.paused || p.finished() {
+		return time.Time{}, false
+	}
+	return p.frameStart.Add(p.anim.FrameDelay(p.frame)), true
+}
+
+// imageOp returns the cached op for the frame being shown
+func (p *CatPic) imageOp() paint.ImageOp {
+	if p.ops == nil {
+		count := 1
+		if p.anim != nil {
+			count = p.anim.FrameCount()
+		}
+		p.ops = make([]paint.ImageOp, count)
+	}
+	if p.ops[p.frame] == (paint.ImageOp{}) {
+		src := p.img
+		if p.anim != nil {
+			src = p.anim.Frame(p.frame)
+		}
+		p.ops[p.frame] = paint.NewImageOp(src)
+	}
+	return p.ops[p.frame]
+}
+
+// Draw lays out the current frame. While an animation plays it asks Gio for
+// a new frame when the next one is due.
 func (p *CatPic) Draw(gtx layout.Context) layout.Dimensions {
-	img := p.GetImage()
-	if img == nil {
+	p.mu.Lock()
+	if p.img == nil {
+		p.mu.Unlock()
 		return layout.Dimensions{Size: gtx.Constraints.Min}
 	}
+	p.advance(gtx.Now)
+	src := p.imageOp()
+	next, playing := p.nextFrameAt()
+	p.mu.Unlock()
+
+	if playing {
+		gtx.Execute(op.InvalidateCmd{At: next})
+	}
 
 	return widget.Image{
-		Src:      paint.NewImageOp(img),
+		Src:      src,
 		Fit:      widget.Contain,
 		Position: layout.Center,
 	}.Layout(gtx)
 }
+
+// Layout fills the constraints with the image, centred, and draws a loading
+// indicator over it while loading: a progress bar when the download size is
+// known, a spinner otherwise.
+func (p *CatPic) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return layout.Stack{Alignment: layout.Center}.Layout(gtx,
+		layout.Stacked(p.Draw),
+		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			if !p.IsLoading() {
+				return layout.Dimensions{}
+			}
+			gtx.Constraints.Min = image.Point{}
+			if progress, ok := p.LoadingProgress(); ok {
+				gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(unit.Dp(200)))
+				return material.ProgressBar(th, progress).Layout(gtx)
+			}
+			size := gtx.Dp(unit.Dp(48))
+			gtx.Constraints = layout.Exact(gtx.Constraints.Constrain(image.Pt(size, size)))
+			return material.Loader(th).Layout(gtx)
+		}),
+	)
+}
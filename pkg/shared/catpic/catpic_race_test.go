@@ -4,6 +4,7 @@ import (
 	"image"
 	"sync"
 	"testing"
+	"time"
 
 	"gioui.org/layout"
 	"gioui.org/op"
@@ -422,3 +423,36 @@ func TestCatPic_NoDeadlock(t *testing.T) {
 	// If timeout fires first, we have a deadlock
 	<-done // Wait for completion (should not deadlock)
 }
+
+// TestCatPic_ConcurrentAnimation tests drawing an animation while it is paused, resumed and replaced
+func TestCatPic_ConcurrentAnimation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping race test in short mode")
+	}
+
+	catPic := NewCatImage(newTestAnimation(5, 0))
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		now := time.Now()
+		for i := 0; i < 500; i++ {
+			drawAt(catPic, now.Add(time.Duration(i)*30*time.Millisecond))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			catPic.TogglePause()
+			_ = catPic.Frame()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			catPic.SetImage(newTestAnimation(i%4+1, i%3-1))
+		}
+	}()
+	wg.Wait()
+}
@@ -3,10 +3,13 @@ package catpic
 import (
 	"image"
 	"testing"
+	"time"
 
 	"gioui.org/layout"
 	"gioui.org/op"
+	"gioui.org/widget/material"
 	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
 )
 
 // TestNewCatImage tests the constructor
@@ -163,6 +166,126 @@ func TestCatPic_LoadingStateTransitions(t *testing.T) {
 	testutil.AssertFalse(t, catPic.IsLoading(), "after second clear")
 }
 
+// TestCatPic_Progress tests download progress while loading
+func TestCatPic_Progress(t *testing.T) {
+	catPic := NewCatImage(nil)
+
+	catPic.SetLoading()
+	received, total := catPic.Progress()
+	testutil.AssertEqual(t, [2]int64{0, -1}, [2]int64{received, total}, "progress after SetLoading")
+
+	catPic.SetProgress(512, 2048)
+	received, total = catPic.Progress()
+	testutil.AssertEqual(t, [2]int64{512, 2048}, [2]int64{received, total}, "progress after SetProgress")
+
+	// a new load starts over
+	catPic.ClearLoading()
+	catPic.SetLoading()
+	received, total = catPic.Progress()
+	testutil.AssertEqual(t, [2]int64{0, -1}, [2]int64{received, total}, "progress after a new SetLoading")
+}
+
+// TestCatPic_LoadingProgress tests the fraction the progress bar shows
+func TestCatPic_LoadingProgress(t *testing.T) {
+	catPic := NewCatImage(nil)
+
+	_, ok := catPic.LoadingProgress()
+	testutil.AssertFalse(t, ok, "no progress when not loading")
+
+	catPic.SetLoading()
+	_, ok = catPic.LoadingProgress()
+	testutil.AssertFalse(t, ok, "no progress before the size is known")
+
+	catPic.SetProgress(512, 2048)
+	progress, ok := catPic.LoadingProgress()
+	testutil.AssertTrue(t, ok, "progress once the size is known")
+	testutil.AssertEqual(t, float32(0.25), progress, "fraction received")
+
+	catPic.SetProgress(100, -1)
+	_, ok = catPic.LoadingProgress()
+	testutil.AssertFalse(t, ok, "no progress for an unknown size")
+
+	catPic.SetProgress(4096, 2048)
+	progress, _ = catPic.LoadingProgress()
+	testutil.AssertEqual(t, float32(1), progress, "fraction is capped at 1")
+
+	catPic.ClearLoading()
+	_, ok = catPic.LoadingProgress()
+	testutil.AssertFalse(t, ok, "no progress after loading")
+}
+
+// TestCatPic_Layout tests that Layout fills the area with or without an indicator
+func TestCatPic_Layout(t *testing.T) {
+	th := material.NewTheme()
+	img := testutil.CreateColorImage(100, 50, 255, 0, 0)
+
+	for _, tt := range []struct {
+		name  string
+		setup func(p *CatPic)
+	}{
+		{"idle", func(p *CatPic) {}},
+		{"spinner", func(p *CatPic) { p.SetLoading() }},
+		{"progress bar", func(p *CatPic) { p.SetLoading(); p.SetProgress(10, 100) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, src := range []image.Image{nil, img} {
+				catPic := NewCatImage(src)
+				tt.setup(catPic)
+
+				var ops op.Ops
+				gtx := layout.Context{
+					Ops:         &ops,
+					Constraints: layout.Constraints{Max: image.Pt(400, 300)},
+				}
+				dims := catPic.Layout(gtx, th)
+				testutil.AssertEqual(t, image.Pt(400, 300), dims.Size, "layout fills the area")
+			}
+		})
+	}
+}
+
+// TestCatPic_Cache tests that cats shown are added to the shared cache
+func TestCatPic_Cache(t *testing.T) {
+	cache := api.NewImageCache(0)
+	catPic := NewCatImage(nil)
+	catPic.SetCache(cache)
+
+	img := testutil.CreateColorImage(3, 3, 255, 0, 0)
+	catPic.SetCat("abc", img, &api.CatMetadata{ID: "abc"})
+	testutil.AssertTrue(t, catPic.GetImage() == image.Image(img), "SetCat shows the image")
+	testutil.AssertEqual(t, 1, cache.Len(), "SetCat caches the image")
+
+	cached, meta, ok := cache.Get("abc")
+	testutil.AssertTrue(t, ok, "the fetch layer finds the cat")
+	testutil.AssertTrue(t, cached == image.Image(img), "cached image")
+	testutil.AssertEqual(t, "abc", meta.ID, "cached metadata")
+
+	t.Run("no_cache", func(t *testing.T) {
+		p := NewCatImage(nil)
+		p.SetCat("abc", img, nil)
+		testutil.AssertTrue(t, p.GetImage() == image.Image(img), "SetCat shows the image")
+	})
+}
+
+// TestCatPic_Metadata tests that the metadata follows the cat shown
+func TestCatPic_Metadata(t *testing.T) {
+	cache := api.NewImageCache(0)
+	catPic := NewCatImage(nil)
+	catPic.SetCache(cache)
+	testutil.AssertNil(t, catPic.Metadata(), "no metadata before a cat")
+
+	img := testutil.CreateColorImage(3, 3, 255, 0, 0)
+	first := &api.CatMetadata{ID: "abc"}
+	catPic.SetCat("abc", img, first)
+	testutil.AssertTrue(t, catPic.Metadata() == first, "SetCat sets the metadata")
+
+	catPic.SetCat("def", img, &api.CatMetadata{ID: "def"})
+	testutil.AssertEqual(t, "def", catPic.Metadata().ID, "SetCat replaces the metadata")
+
+	catPic.SetImage(img)
+	testutil.AssertNil(t, catPic.Metadata(), "SetImage clears the metadata")
+}
+
 // TestCatPic_Draw_NilImage tests Draw with nil image
 func TestCatPic_Draw_NilImage(t *testing.T) {
 	catPic := NewCatImage(nil)
@@ -472,3 +595,133 @@ func TestCatPic_Draw_LargeConstraints(t *testing.T) {
 		t.Errorf("Aspect ratio not preserved with large constraints: expected %.4f, got %.4f", expectedAspect, scaledAspect)
 	}
 }
+
+var _ Animated = (*api.Animation)(nil)
+
+// newTestAnimation builds a frames long animation, 100ms per frame
+func newTestAnimation(frames, loopCount int) *api.Animation {
+	anim := &api.Animation{LoopCount: loopCount}
+	for i := 0; i < frames; i++ {
+		anim.Frames = append(anim.Frames, testutil.CreateColorImage(10, 10, uint8(i*50), 0, 0))
+		anim.Delays = append(anim.Delays, 100*time.Millisecond)
+	}
+	return anim
+}
+
+// drawAt draws catPic as if the frame was rendered at now
+func drawAt(catPic *CatPic, now time.Time) layout.Dimensions {
+	var ops op.Ops
+	gtx := layout.Context{
+		Ops: &ops,
+		Now: now,
+		Constraints: layout.Constraints{
+			Max: image.Pt(100, 100),
+		},
+	}
+	return catPic.Draw(gtx)
+}
+
+// TestCatPic_Animation_Playback tests that Draw steps through the frames
+func TestCatPic_Animation_Playback(t *testing.T) {
+	catPic := NewCatImage(newTestAnimation(3, 0))
+	testutil.AssertTrue(t, catPic.IsAnimated(), "should be animated")
+
+	t0 := time.Now()
+	steps := []struct {
+		after time.Duration
+		frame int
+	}{
+		{0, 0},
+		{50 * time.Millisecond, 0},
+		{150 * time.Millisecond, 1},
+		{250 * time.Millisecond, 2},
+		{310 * time.Millisecond, 0}, // loops forever
+		{450 * time.Millisecond, 1},
+	}
+	for _, step := range steps {
+		dims := drawAt(catPic, t0.Add(step.after))
+		testutil.AssertTrue(t, dims.Size.X > 0, "frame should be drawn")
+		testutil.AssertEqual(t, step.frame, catPic.Frame(), "frame after "+step.after.String())
+	}
+
+	next, playing := catPic.nextFrameAt()
+	testutil.AssertTrue(t, playing, "should be playing")
+	testutil.AssertEqual(t, t0.Add(500*time.Millisecond), next, "next frame due")
+}
+
+// TestCatPic_Animation_PlayCount tests that finite animations stop on the last frame
+func TestCatPic_Animation_PlayCount(t *testing.T) {
+	catPic := NewCatImage(newTestAnimation(2, -1)) // plays once
+
+	t0 := time.Now()
+	drawAt(catPic, t0)
+	drawAt(catPic, t0.Add(time.Second))
+	testutil.AssertEqual(t, 1, catPic.Frame(), "stays on the last frame")
+	_, playing := catPic.nextFrameAt()
+	testutil.AssertFalse(t, playing, "finished animation requests no frames")
+
+	catPic.Resume()
+	drawAt(catPic, t0.Add(2*time.Second))
+	testutil.AssertEqual(t, 0, catPic.Frame(), "Resume replays a finished animation")
+}
+
+// TestCatPic_Animation_Pause tests pausing and resuming playback
+func TestCatPic_Animation_Pause(t *testing.T) {
+	catPic := NewCatImage(newTestAnimation(3, 0))
+
+	t0 := time.Now()
+	drawAt(catPic, t0)
+	drawAt(catPic, t0.Add(50*time.Millisecond))
+
+	catPic.Pause()
+	testutil.AssertTrue(t, catPic.IsPaused(), "should be paused")
+	drawAt(catPic, t0.Add(60*time.Millisecond))
+	drawAt(catPic, t0.Add(5*time.Second))
+	testutil.AssertEqual(t, 0, catPic.Frame(), "paused on the first frame")
+	_, playing := catPic.nextFrameAt()
+	testutil.AssertFalse(t, playing, "paused animation requests no frames")
+
+	catPic.TogglePause()
+	testutil.AssertFalse(t, catPic.IsPaused(), "should be playing")
+	drawAt(catPic, t0.Add(5*time.Second))
+	testutil.AssertEqual(t, 0, catPic.Frame(), "time spent paused is not counted")
+	drawAt(catPic, t0.Add(5*time.Second+50*time.Millisecond))
+	testutil.AssertEqual(t, 1, catPic.Frame(), "resumes where it stopped")
+}
+
+// TestCatPic_Animation_SetImage tests that a new image restarts playback
+func TestCatPic_Animation_SetImage(t *testing.T) {
+	catPic := NewCatImage(newTestAnimation(3, 0))
+
+	t0 := time.Now()
+	drawAt(catPic, t0)
+	drawAt(catPic, t0.Add(150*time.Millisecond))
+	testutil.AssertEqual(t, 1, catPic.Frame(), "second frame")
+
+	catPic.SetImage(newTestAnimation(4, 0))
+	testutil.AssertEqual(t, 0, catPic.Frame(), "new animation starts at the first frame")
+
+	catPic.SetImage(testutil.CreateColorImage(10, 10, 0, 0, 0))
+	testutil.AssertFalse(t, catPic.IsAnimated(), "static image is not animated")
+	drawAt(catPic, t0.Add(time.Second))
+	_, playing := catPic.nextFrameAt()
+	testutil.AssertFalse(t, playing, "static image requests no frames")
+
+	catPic.SetImage(newTestAnimation(1, 0))
+	testutil.AssertFalse(t, catPic.IsAnimated(), "a single frame is not animated")
+}
+
+// TestCatPic_Animation_FarBehind tests that a long gap doesn't replay every missed frame
+func TestCatPic_Animation_FarBehind(t *testing.T) {
+	catPic := NewCatImage(newTestAnimation(3, 0))
+
+	t0 := time.Now()
+	drawAt(catPic, t0)
+	testutil.AssertNoPanic(t, func() {
+		drawAt(catPic, t0.Add(24*time.Hour))
+	}, "Draw after a long gap")
+
+	next, playing := catPic.nextFrameAt()
+	testutil.AssertTrue(t, playing, "still playing")
+	testutil.AssertTrue(t, next.After(t0.Add(24*time.Hour)), "next frame is in the future")
+}
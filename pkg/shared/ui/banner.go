@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// errorBanner tells the user why the last fetch failed and offers to retry
+// it. It is set from fetch goroutines and laid out by the event loop.
+type errorBanner struct {
+	mu    sync.Mutex
+	msg   string
+	retry widget.Clickable
+}
+
+// Show replaces the banner's message with one describing err
+func (b *errorBanner) Show(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.msg = describeError(err)
+}
+
+// Clear hides the banner
+func (b *errorBanner) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.msg = ""
+}
+
+// Message returns the message shown, empty when the banner is hidden
+func (b *errorBanner) Message() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.msg
+}
+
+// RetryClicked reports whether the Retry button was clicked
+func (b *errorBanner) RetryClicked(gtx layout.Context) bool {
+	return b.retry.Clicked(gtx)
+}
+
+// Layout renders the banner, taking no space while it is hidden
+func (b *errorBanner) Layout(gtx layout.Context, th *material.Theme, insetPixels unit.Dp) layout.Dimensions {
+	msg := b.Message()
+	if msg == "" {
+		return layout.Dimensions{}
+	}
+
+	return layout.UniformInset(insetPixels).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Stack{}.Layout(gtx,
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				rect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(8))
+				paint.FillShape(gtx.Ops, color.NRGBA{R: 255, G: 85, B: 85, A: 255}, rect.Op(gtx.Ops))
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}),
+			layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+				return layout.UniformInset(8).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+							label := material.Body1(th, msg)
+							label.Color = color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+							return label.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							button := material.Button(th, &b.retry, "Retry")
+							button.CornerRadius = unit.Dp(16)
+							button.Background = color.NRGBA{R: 40, G: 42, B: 54, A: 255}
+							button.Color = color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+							return layout.Inset{Left: 8}.Layout(gtx, button.Layout)
+						}),
+					)
+				})
+			}),
+		)
+	})
+}
+
+// describeError turns a fetch error into a message for the user
+func describeError(err error) string {
+	var statusErr *api.HTTPStatusError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, api.ErrTimeout):
+		return "The cat server took too long to answer."
+	case errors.Is(err, api.ErrCatNotFound):
+		return "That cat could not be found."
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("The cat server answered %s.", statusErr.Status)
+	case errors.Is(err, api.ErrNotCached):
+		return "That cat isn't cached, and we are offline."
+	case errors.Is(err, api.ErrImageTooLarge):
+		return "The cat picture is too large to show."
+	case errors.Is(err, api.ErrMIMEMismatch):
+		return "The cat server didn't send a picture."
+	case errors.Is(err, api.ErrImageDecode), errors.Is(err, api.ErrUnsupportedFormat):
+		return "The cat picture could not be decoded."
+	case errors.Is(err, api.ErrMetadataDecode):
+		return "The cat server sent a reply we couldn't read."
+	default:
+		return fmt.Sprintf("Fetching a cat failed: %v", err)
+	}
+}
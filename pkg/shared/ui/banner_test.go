@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// TestDescribeError tests the message shown for each kind of fetch error
+func TestDescribeError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"timeout", fmt.Errorf("%w: %w", api.ErrTimeout, context.DeadlineExceeded), "took too long"},
+		{"not found", fmt.Errorf("%w: %q", api.ErrCatNotFound, "abc"), "could not be found"},
+		{"http status", &api.HTTPStatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}, "503 Service Unavailable"},
+		{"wrapped http status", fmt.Errorf("fetching metadata: %w", &api.HTTPStatusError{StatusCode: 500, Status: "500 Internal Server Error"}), "500 Internal Server Error"},
+		{"offline", api.ErrNotCached, "offline"},
+		{"too large", fmt.Errorf("%w: %w", api.ErrImageDecode, api.ErrImageTooLarge), "too large"},
+		{"not an image", fmt.Errorf("%w: expected an image, got text/html", api.ErrMIMEMismatch), "didn't send a picture"},
+		{"decode", fmt.Errorf("%w: %w", api.ErrImageDecode, api.ErrUnsupportedFormat), "could not be decoded"},
+		{"metadata", api.ErrMetadataDecode, "couldn't read"},
+		{"other", fmt.Errorf("connection refused"), "connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeError(tt.err)
+			if tt.want == "" {
+				testutil.AssertEqual(t, "", got, "message")
+				return
+			}
+			testutil.AssertTrue(t, strings.Contains(got, tt.want), fmt.Sprintf("message %q should mention %q", got, tt.want))
+		})
+	}
+}
+
+// TestErrorBanner tests showing and clearing the error banner
+func TestErrorBanner(t *testing.T) {
+	var b errorBanner
+	testutil.AssertEqual(t, "", b.Message(), "banner should start hidden")
+
+	b.Show(api.ErrNotCached)
+	testutil.AssertEqual(t, describeError(api.ErrNotCached), b.Message(), "banner should describe the error")
+
+	b.Clear()
+	testutil.AssertEqual(t, "", b.Message(), "banner should hide after clear")
+}
@@ -1,18 +1,64 @@
 package ui
 
 import (
+	"context"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/bmj2728/catfetch/pkg/shared/api"
 )
 
+// Offline makes the app show only cats cached by earlier runs, never going
+// to the network. Set it before Run.
+var Offline bool
+
+// catCache keeps fetched cats on disk between runs, nil when the user has no
+// cache directory. It is opened on first use rather than at init, so tests
+// can point the cache directory elsewhere first.
+var catCache = sync.OnceValue(newCatCache)
+
+// imageCache holds decoded cats, shared by the fetch layer and the CatPic
+var imageCache = api.NewImageCache(api.DefaultImageCacheSize)
+
+func newCatCache() *api.DiskCache {
+	dir := api.DefaultDiskCacheDir()
+	if dir == "" {
+		return nil
+	}
+	return api.NewDiskCache(dir, api.DefaultDiskCacheSize)
+}
+
+// flushCatCache saves the access times of cats read from the disk cache
+// since its index was last written
+func flushCatCache() {
+	if c := catCache(); c != nil {
+		if err := c.Flush(); err != nil {
+			log.Printf("Error saving image cache: %v", err)
+		}
+	}
+}
+
+// newClient returns the client the GUI fetches cats with. An attempt may
+// take 30s, and the default retry policy's MaxElapsed holds the whole fetch,
+// retries included, to the same 30s, so a hung server fails once. When
+// Offline is set it only serves cats from the disk cache.
+func newClient(progress api.ProgressFunc) *api.Client {
+	return api.NewClient().WithTimeout(30 * time.Second).WithProgress(progress).WithDiskCache(catCache()).WithImageCache(imageCache).WithOffline(Offline)
+}
+
+// NewPrefetcher keeps api.DefaultPrefetchSize random cats ready for the
+// fetch button, reporting the progress of each download to progress
+func NewPrefetcher(ctx context.Context, progress api.ProgressFunc) *api.Prefetcher {
+	return api.NewPrefetcher(ctx, newClient(progress), api.NewCatURL(), api.DefaultPrefetchSize)
+}
+
 func HandleButtonClick() (image.Image, *api.CatMetadata, error) {
-	img, metadata, err := api.RequestRandomCat(30 * time.Second)
+	img, metadata, err := newClient(nil).RandomCat(context.Background(), api.NewCatURL())
 	if err != nil {
 		log.Printf("Error fetching image: %v", err)
 		return nil, nil, err
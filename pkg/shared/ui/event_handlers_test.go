@@ -1,15 +1,37 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
 )
 
+// TestMain points the user's cache directory at a temporary one, so cats the
+// tests fetch through newClient stay out of the real disk cache
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "catfetch-ui-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// os.UserCacheDir looks at XDG_CACHE_HOME on Unix, HOME on macOS and
+	// LocalAppData on Windows
+	for _, env := range []string{"XDG_CACHE_HOME", "HOME", "LocalAppData"} {
+		os.Setenv(env, dir)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // TestHandleButtonClick_Success tests successful button click handling
 func TestHandleButtonClick_Success(t *testing.T) {
 	// Create image server
@@ -223,3 +245,20 @@ func TestHandleButtonClick_NilHandling(t *testing.T) {
 // func (h *EventHandler) HandleButtonClick() (image.Image, *api.CatMetadata, error)
 //
 // For now, these tests document the expected behavior and verify the function exists.
+
+// TestHandleButtonClick_Offline tests that Offline keeps fetches off the network
+func TestHandleButtonClick_Offline(t *testing.T) {
+	// earlier tests leave cats in the shared test cache, so start empty
+	empty := api.NewDiskCache(t.TempDir(), api.DefaultDiskCacheSize)
+	oldCache := catCache
+	catCache = func() *api.DiskCache { return empty }
+	defer func() { catCache = oldCache }()
+
+	Offline = true
+	defer func() { Offline = false }()
+
+	img, meta, err := HandleButtonClick()
+	testutil.AssertTrue(t, errors.Is(err, api.ErrNotCached), "nothing is cached in the test cache directory")
+	testutil.AssertNil(t, img, "no image")
+	testutil.AssertNil(t, meta, "no metadata")
+}
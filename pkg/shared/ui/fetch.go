@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"context"
+	"image"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// fetchControl runs the fetch button's fetches one at a time. Each fetch has
+// a context of its own, cancelled by the Cancel button or with the window's
+// context when it is destroyed. The fetch button is disabled while a cat
+// loads, so a fetch is only begun once the last one ended or was cancelled.
+type fetchControl struct {
+	mu     sync.Mutex
+	id     int // counts fetches, to tell the current one from abandoned ones
+	cancel context.CancelFunc
+}
+
+// begin starts a fetch. The fetch calls end once it is finished, which
+// reports whether it is still the current fetch, rather than one cancelled
+// since, and so should update the window.
+func (f *fetchControl) begin(parent context.Context) (ctx context.Context, end func() bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.id++
+	id := f.id
+	ctx, cancel := context.WithCancel(parent)
+	f.cancel = cancel
+
+	return ctx, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		cancel()
+		if f.id != id {
+			return false
+		}
+		f.cancel = nil
+		return true
+	}
+}
+
+// Cancel abandons the running fetch. Its end reports false, so it leaves the
+// window alone.
+func (f *fetchControl) Cancel() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cancel != nil {
+		f.cancel()
+		f.cancel = nil
+		f.id++
+	}
+}
+
+// Running reports whether a fetch has begun and not yet ended or been
+// cancelled
+func (f *fetchControl) Running() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cancel != nil
+}
+
+// queueProgress passes the prefetcher's download progress on to report only
+// while a fetch waits on an empty queue, when the download in flight is the
+// cat about to be shown. Downloads refilling the queue in the background
+// would otherwise move the loading bar.
+type queueProgress struct {
+	waiting atomic.Bool
+	report  api.ProgressFunc
+}
+
+// Report is the prefetcher's ProgressFunc
+func (q *queueProgress) Report(received, total int64) {
+	if q.waiting.Load() {
+		q.report(received, total)
+	}
+}
+
+// nextCat takes the next prefetched cat, reporting the download's progress
+// to q when it has to wait for one. A retry instead fetches a fresh cat with
+// c and the prefetcher's current settings, so it asks the server again
+// rather than taking whatever the prefetcher last got from it; c reports
+// its own progress.
+func nextCat(ctx context.Context, p *api.Prefetcher, q *queueProgress, c *api.Client, retry bool) (image.Image, *api.CatMetadata, *api.CatURL, error) {
+	if !retry {
+		if p.Ready() == 0 {
+			q.waiting.Store(true)
+			defer q.waiting.Store(false)
+		}
+		return p.NextCat(ctx)
+	}
+	u := p.URL()
+	img, meta, err := c.Fetch(ctx, u)
+	return img, meta, u, err
+}
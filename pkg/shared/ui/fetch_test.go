@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// TestFetchControl_Begin tests that a fetch runs from begin until it ends
+func TestFetchControl_Begin(t *testing.T) {
+	var f fetchControl
+	testutil.AssertFalse(t, f.Running(), "nothing should run before begin")
+
+	ctx, end := f.begin(context.Background())
+	testutil.AssertTrue(t, f.Running(), "fetch should run after begin")
+	testutil.AssertNil(t, ctx.Err(), "fetch context should be live")
+
+	testutil.AssertTrue(t, end(), "the only fetch should be current")
+	testutil.AssertFalse(t, f.Running(), "nothing should run after end")
+	testutil.AssertNotNil(t, ctx.Err(), "fetch context should be released after end")
+}
+
+// TestFetchControl_BeginAfterCancel tests that a cancelled fetch ending late leaves the next one alone
+func TestFetchControl_BeginAfterCancel(t *testing.T) {
+	var f fetchControl
+	first, endFirst := f.begin(context.Background())
+	f.Cancel()
+	second, endSecond := f.begin(context.Background())
+
+	testutil.AssertNotNil(t, first.Err(), "cancelled fetch should stay cancelled")
+	testutil.AssertNil(t, second.Err(), "new fetch should be live")
+
+	testutil.AssertFalse(t, endFirst(), "cancelled fetch finishing late should not be current")
+	testutil.AssertTrue(t, f.Running(), "the late finish should leave the new fetch running")
+	testutil.AssertTrue(t, endSecond(), "new fetch should be current")
+}
+
+// TestFetchControl_Cancel tests abandoning the running fetch
+func TestFetchControl_Cancel(t *testing.T) {
+	var f fetchControl
+	ctx, end := f.begin(context.Background())
+
+	f.Cancel()
+	testutil.AssertNotNil(t, ctx.Err(), "fetch context should be cancelled")
+	testutil.AssertFalse(t, f.Running(), "nothing should run after cancel")
+	testutil.AssertFalse(t, end(), "cancelled fetch should not be current")
+
+	testutil.AssertNoPanic(t, f.Cancel, "cancel with nothing running")
+}
+
+// TestFetchControl_ParentCancelled tests that fetches stop with the window's context
+func TestFetchControl_ParentCancelled(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	var f fetchControl
+	ctx, _ := f.begin(parent)
+
+	// destroying the window cancels the context Run fetches under
+	cancel()
+	testutil.AssertNotNil(t, ctx.Err(), "fetch should stop with the window")
+}
+
+// TestNextCat_Retry tests that Retry asks the server again instead of
+// taking what the prefetcher got during an outage
+func TestNextCat_Retry(t *testing.T) {
+	var requests atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if failing.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("json") == "true" {
+			fmt.Fprintf(w, `{"id":"retry-%d","url":"/cat/retry-%d","mimetype":"image/png"}`, n, n)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(testutil.ValidPNGBytes())
+	}})
+
+	client := api.NewClient().WithBaseURL(server.URL).WithRetryPolicy(api.NoRetry)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prefetcher := api.NewPrefetcher(ctx, client, api.NewCatURL().WithWidth(200), 1)
+
+	_, _, _, err := nextCat(ctx, prefetcher, &queueProgress{}, client, false)
+	var statusErr *api.HTTPStatusError
+	testutil.AssertTrue(t, errors.As(err, &statusErr), "the outage should be reported")
+
+	failing.Store(false)
+	before := requests.Load()
+	img, meta, u, err := nextCat(ctx, prefetcher, &queueProgress{}, client, true)
+	testutil.AssertNoError(t, err, "Retry should succeed once the server is back")
+	testutil.AssertNotNil(t, img, "image")
+	testutil.AssertTrue(t, requests.Load() > before, "Retry should reach the server")
+	testutil.AssertNotNil(t, meta, "metadata")
+	generated, err := u.Generate()
+	testutil.AssertNoError(t, err, "Generate should succeed")
+	testutil.AssertEqual(t, "https://cataas.com/cat?width=200", generated, "Retry uses the current settings")
+}
+
+// TestNextCat_Progress tests that only the download a fetch waits for moves
+// the loading bar, not those refilling the queue in the background
+func TestNextCat_Progress(t *testing.T) {
+	release := make(chan struct{})
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("json") == "true" {
+			fmt.Fprint(w, `{"id":"progress","url":"/cat/progress","mimetype":"image/png"}`)
+			return
+		}
+		// hold the image until the test is waiting for it
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(testutil.ValidPNGBytes())
+	}})
+
+	var reports atomic.Int32
+	q := &queueProgress{report: func(received, total int64) { reports.Add(1) }}
+	q.Report(1, 2)
+	testutil.AssertEqual(t, int32(0), reports.Load(), "nothing is reported while no fetch waits")
+
+	client := api.NewClient().WithBaseURL(server.URL).WithRetryPolicy(api.NoRetry).WithProgress(q.Report)
+	prefetcher := api.NewPrefetcher(context.Background(), client, nil, 1)
+	t.Cleanup(prefetcher.Stop)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, _, err := nextCat(context.Background(), prefetcher, q, nil, false)
+		done <- err
+	}()
+	for !q.waiting.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	testutil.AssertNoError(t, <-done, "nextCat should succeed")
+	testutil.AssertTrue(t, reports.Load() > 0, "the download waited for is reported")
+	testutil.AssertFalse(t, q.waiting.Load(), "nothing waits once the cat is taken")
+
+	// the queue refills in the background, unreported
+	before := reports.Load()
+	for prefetcher.Ready() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	testutil.AssertEqual(t, before, reports.Load(), "refilling the queue is not reported")
+}
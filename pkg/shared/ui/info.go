@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"net/url"
+	"strings"
+
+	"gioui.org/io/clipboard"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// infoPanel shows the metadata of the cat on screen under the image. It
+// starts collapsed, clicking its header opens and closes it.
+type infoPanel struct {
+	// resolves the server's relative cat URLs, cataas.com when nil
+	client   *api.Client
+	toggle   widget.Clickable
+	copyURL  widget.Clickable
+	tags     widget.List
+	expanded bool
+}
+
+// infoRow is one line of the panel, a label and its value
+type infoRow struct {
+	label, value string
+}
+
+// infoRows formats the metadata of a cat and the size of its image
+func infoRows(meta *api.CatMetadata, img image.Image) []infoRow {
+	rows := []infoRow{{"ID", meta.ID}}
+	if !meta.CreatedAt.IsZero() {
+		rows = append(rows, infoRow{"Created", meta.CreatedAt.Local().Format("2 Jan 2006 15:04 MST")})
+	}
+	if meta.MIMEType != "" {
+		rows = append(rows, infoRow{"Type", meta.MIMEType})
+	}
+	if img != nil {
+		size := img.Bounds().Size()
+		rows = append(rows, infoRow{"Size", fmt.Sprintf("%d x %d px", size.X, size.Y)})
+	}
+	return rows
+}
+
+// catLink returns an absolute link to the cat, "" when there is none. The
+// server often reports relative URLs, those are resolved against c's base
+// URL, or cataas.com when c is nil. Without a URL the link is made from the
+// cat's id.
+func catLink(c *api.Client, meta *api.CatMetadata) string {
+	if c == nil {
+		c = api.NewClient()
+	}
+	ref := meta.URL
+	if ref == "" {
+		if meta.ID == "" {
+			return ""
+		}
+		ref = "/cat/" + url.PathEscape(meta.ID)
+	}
+	link, err := c.ResolveURL(ref)
+	if err != nil {
+		return ""
+	}
+	return link
+}
+
+// Update handles the panel's clicks, copying a link to the cat to the
+// clipboard when asked
+func (p *infoPanel) Update(gtx layout.Context, meta *api.CatMetadata) {
+	if p.toggle.Clicked(gtx) {
+		p.expanded = !p.expanded
+	}
+	if !p.copyURL.Clicked(gtx) || meta == nil {
+		return
+	}
+	if link := catLink(p.client, meta); link != "" {
+		gtx.Execute(clipboard.WriteCmd{
+			Type: "application/text",
+			Data: io.NopCloser(strings.NewReader(link)),
+		})
+	}
+}
+
+// Layout renders the panel for the cat shown, nothing when there is no
+// metadata to show
+func (p *infoPanel) Layout(gtx layout.Context, th *material.Theme, meta *api.CatMetadata, img image.Image, insetPixels unit.Dp) layout.Dimensions {
+	if meta == nil {
+		return layout.Dimensions{}
+	}
+	p.Update(gtx, meta)
+
+	fg := color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+	header := "▸ Cat info"
+	if p.expanded {
+		header = "▾ Cat info"
+	}
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					return material.Clickable(gtx, &p.toggle, func(gtx layout.Context) layout.Dimensions {
+						label := material.Body1(th, header)
+						label.Color = fg
+						return layout.UniformInset(4).Layout(gtx, label.Layout)
+					})
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					if catLink(p.client, meta) == "" {
+						gtx = gtx.Disabled()
+					}
+					button := material.Button(th, &p.copyURL, "Copy URL")
+					button.CornerRadius = unit.Dp(16)
+					button.Background = color.NRGBA{R: 98, G: 114, B: 164, A: 255}
+					button.Color = fg
+					return button.Layout(gtx)
+				}),
+			)
+		}),
+	}
+	if p.expanded {
+		for _, row := range infoRows(meta, img) {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layoutInfoRow(gtx, th, row)
+			}))
+		}
+		if len(meta.Tags) > 0 {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTags(gtx, th, meta.Tags)
+			}))
+		}
+	}
+
+	return layout.UniformInset(insetPixels).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+func layoutInfoRow(gtx layout.Context, th *material.Theme, row infoRow) layout.Dimensions {
+	return layout.Inset{Top: 2, Bottom: 2, Left: 4}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = gtx.Dp(80)
+				label := material.Body2(th, row.label)
+				label.Color = color.NRGBA{R: 98, G: 114, B: 164, A: 255}
+				return label.Layout(gtx)
+			}),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				label := material.Body2(th, row.value)
+				label.Color = color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+				return label.Layout(gtx)
+			}),
+		)
+	})
+}
+
+// layoutTags renders the tags as a row of chips, scrolling sideways when
+// they don't fit
+func (p *infoPanel) layoutTags(gtx layout.Context, th *material.Theme, tags []string) layout.Dimensions {
+	p.tags.Axis = layout.Horizontal
+	return layout.Inset{Top: 4}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return material.List(th, &p.tags).Layout(gtx, len(tags), func(gtx layout.Context, i int) layout.Dimensions {
+			return layout.Inset{Left: 4, Right: 4}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layoutChip(gtx, th, tags[i])
+			})
+		})
+	})
+}
+
+// layoutChip renders text on a rounded background
+func layoutChip(gtx layout.Context, th *material.Theme, text string) layout.Dimensions {
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+			rect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Constraints.Min.Y/2)
+			paint.FillShape(gtx.Ops, color.NRGBA{R: 68, G: 71, B: 90, A: 255}, rect.Op(gtx.Ops))
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}),
+		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			label := material.Caption(th, text)
+			label.Color = color.NRGBA{R: 139, G: 233, B: 253, A: 255}
+			return layout.Inset{Top: 2, Bottom: 2, Left: 8, Right: 8}.Layout(gtx, label.Layout)
+		}),
+	)
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// TestInfoRows tests the rows shown for full and partial metadata
+func TestInfoRows(t *testing.T) {
+	created := time.Date(2024, 3, 9, 14, 30, 0, 0, time.UTC)
+	meta := &api.CatMetadata{
+		ID:        "abc123",
+		Tags:      []string{"cute", "orange"},
+		CreatedAt: created,
+		URL:       "https://cataas.com/cat/abc123",
+		MIMEType:  "image/jpeg",
+	}
+
+	rows := infoRows(meta, testutil.CreateColorImage(640, 480, 0, 0, 0))
+	testutil.AssertEqual(t, []infoRow{
+		{"ID", "abc123"},
+		{"Created", created.Local().Format("2 Jan 2006 15:04 MST")},
+		{"Type", "image/jpeg"},
+		{"Size", "640 x 480 px"},
+	}, rows, "rows for full metadata")
+
+	rows = infoRows(&api.CatMetadata{ID: "abc123"}, nil)
+	testutil.AssertEqual(t, []infoRow{{"ID", "abc123"}}, rows, "unknown fields are left out")
+}
+
+// TestInfoPanel_Layout tests that the panel hides without metadata and grows when expanded
+func TestInfoPanel_Layout(t *testing.T) {
+	th := material.NewTheme()
+	meta := &api.CatMetadata{ID: "abc123", Tags: []string{"cute", "orange"}, URL: "https://cataas.com/cat/abc123"}
+	img := testutil.CreateColorImage(64, 48, 0, 0, 0)
+
+	layoutPanel := func(p *infoPanel, meta *api.CatMetadata) layout.Dimensions {
+		var ops op.Ops
+		gtx := layout.Context{
+			Ops:         &ops,
+			Constraints: layout.Constraints{Max: image.Pt(400, 300)},
+		}
+		return p.Layout(gtx, th, meta, img, 12)
+	}
+
+	var p infoPanel
+	testutil.AssertEqual(t, layout.Dimensions{}, layoutPanel(&p, nil), "no panel without metadata")
+
+	collapsed := layoutPanel(&p, meta)
+	testutil.AssertTrue(t, collapsed.Size.Y > 0, "collapsed panel shows its header")
+
+	p.expanded = true
+	expanded := layoutPanel(&p, meta)
+	testutil.AssertTrue(t, expanded.Size.Y > collapsed.Size.Y, "expanded panel shows the details")
+}
+
+// TestCatLink tests making absolute links to cats on the client's server
+func TestCatLink(t *testing.T) {
+	local := api.NewClient().WithBaseURL("http://localhost:1234")
+	tests := []struct {
+		name   string
+		client *api.Client
+		meta   *api.CatMetadata
+		want   string
+	}{
+		{"absolute", local, &api.CatMetadata{ID: "abc123", URL: "https://example.com/cat/abc123"}, "https://example.com/cat/abc123"},
+		{"relative", local, &api.CatMetadata{ID: "abc123", URL: "/cat/abc123?fontSize=20"}, "http://localhost:1234/cat/abc123?fontSize=20"},
+		{"relative_no_client", nil, &api.CatMetadata{ID: "abc123", URL: "/cat/abc123"}, "https://cataas.com/cat/abc123"},
+		{"missing_url", local, &api.CatMetadata{ID: "abc123"}, "http://localhost:1234/cat/abc123"},
+		{"nothing", local, &api.CatMetadata{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertEqual(t, tt.want, catLink(tt.client, tt.meta), "link")
+		})
+	}
+}
+
+// TestInfoPanel_CopyURL tests that clicking Copy URL puts the cat's link on the clipboard
+func TestInfoPanel_CopyURL(t *testing.T) {
+	th := material.NewTheme()
+	meta := &api.CatMetadata{ID: "abc123", URL: "/cat/abc123"}
+	var p infoPanel
+	var r input.Router
+	var ops op.Ops
+	const inset = 12
+
+	frame := func() layout.Dimensions {
+		ops.Reset()
+		gtx := layout.Context{
+			Ops:         &ops,
+			Constraints: layout.Constraints{Max: image.Pt(400, 300)},
+			Source:      r.Source(),
+		}
+		dims := p.Layout(gtx, th, meta, nil, inset)
+		r.Frame(&ops)
+		return dims
+	}
+
+	// a button like Copy URL, laid out alone to learn its width
+	var measure op.Ops
+	button := material.Button(th, new(widget.Clickable), "Copy URL").Layout(layout.Context{
+		Ops:         &measure,
+		Constraints: layout.Constraints{Max: image.Pt(400, 300)},
+	})
+
+	// the collapsed panel is its header row within the inset, and the
+	// Copy URL button ends that row, at the right
+	dims := frame()
+	pos := f32.Pt(float32(dims.Size.X-inset-button.Size.X/2), float32(dims.Size.Y/2))
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+	)
+	frame()
+
+	_, content, ok := r.WriteClipboard()
+	testutil.AssertTrue(t, ok, "clicking Copy URL writes the clipboard")
+	testutil.AssertEqual(t, "https://cataas.com/cat/abc123", string(content), "the relative URL is made absolute")
+	testutil.AssertFalse(t, p.expanded, "the click should not reach the header toggle")
+}
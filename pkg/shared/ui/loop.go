@@ -1,13 +1,16 @@
 package ui
 
 import (
+	"context"
 	"image"
 	"image/color"
 	//"image"
 	"log"
+	"time"
 
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
 	"github.com/bmj2728/catfetch/pkg/shared/catpic"
 
 	"gioui.org/app"
@@ -18,14 +21,101 @@ import (
 	"gioui.org/widget/material"
 )
 
+// settingsDelay is how long typing has to pause before the prefetcher
+// follows the says settings
+const settingsDelay = 500 * time.Millisecond
+
 func Run(w *app.Window) error {
 	// button
 	var fetchButton widget.Clickable
+	// shown while loading, abandons the fetch
+	var cancelButton widget.Clickable
+	// the fetch in flight, if any
+	var fetches fetchControl
+	// why the last fetch failed, with a Retry button
+	var banner errorBanner
+	// metadata of the cat shown
+	var info infoPanel
+	// makes fetched cats say something
+	says := newSaysEditor()
+	// clicking the picture pauses or resumes an animated cat
+	var imageClick widget.Clickable
 	// thread-safe image wrapper
 	var currentImage catpic.CatPic //threadsafe wrapper for image.Image
+	currentImage.SetCache(imageCache)
 	// Ops list
 	var ops op.Ops
 
+	// cats are fetched ahead of the button so the next one shows at once
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	showProgress := func(received, total int64) {
+		currentImage.SetProgress(received, total)
+		if currentImage.IsLoading() {
+			w.Invalidate()
+		}
+	}
+	// background downloads only move the loading bar while a fetch waits
+	prefetchProgress := &queueProgress{report: showProgress}
+	prefetcher := NewPrefetcher(ctx, prefetchProgress.Report)
+	// Retry fetches with this rather than taking a prefetched cat, and the
+	// info panel links to cats on its server
+	client := newClient(showProgress)
+	info.client = client
+
+	// narrows fetches to a tag once the tags have loaded, counting the cats
+	// of the tags on screen unless offline
+	var counts *tagCounter
+	if !Offline {
+		counts = newTagCounter(ctx, api.AvailableTags, newClient(nil), w.Invalidate)
+	}
+	tags := newTagPicker(api.AvailableTags, counts)
+	tags.offline = Offline
+
+	// redraw once the tags arrive to enable the picker
+	go func() {
+		select {
+		case <-api.AvailableTags.Ready():
+			w.Invalidate()
+		case <-ctx.Done():
+		}
+	}()
+
+	// settings being typed are applied once the user pauses, rather than
+	// restarting the prefetcher on every key
+	var settingsDue time.Time
+	applySettings := func() {
+		settingsDue = time.Time{}
+		prefetcher.SetURL(says.Apply(tags.Apply(api.NewCatURL())))
+	}
+
+	// fetch starts fetching the next cat. The Retry button reissues a failed
+	// fetch, asking the server again with the current settings.
+	fetch := func(retry bool) {
+		if !settingsDue.IsZero() {
+			applySettings()
+		}
+		fetchCtx, end := fetches.begin(ctx)
+		banner.Clear()
+		currentImage.SetLoading()
+		go func() {
+			img, meta, u, err := nextCat(fetchCtx, prefetcher, prefetchProgress, client, retry)
+			switch {
+			case fetchCtx.Err() != nil:
+				// cancelled, whatever NextCat returned is dropped
+			case err != nil:
+				log.Printf("Error handling button click: %v", err)
+				banner.Show(err)
+			default:
+				currentImage.SetCat(api.RenderKey(meta.ID, u), img, meta)
+			}
+			if end() {
+				currentImage.ClearLoading()
+			}
+			w.Invalidate()
+		}()
+	}
+
 	newBg := color.NRGBA{R: 40, G: 42, B: 54, A: 255}
 
 	// Theme for material widgets
@@ -34,6 +124,9 @@ func Run(w *app.Window) error {
 	for {
 		switch e := w.Event().(type) {
 		case app.DestroyEvent:
+			// stops the fetch in flight and the prefetcher with it
+			cancel()
+			flushCatCache()
 			return e.Err
 
 		case app.FrameEvent:
@@ -47,18 +140,36 @@ func Run(w *app.Window) error {
 			paint.FillShape(&ops, newBg, winRect.Op())
 
 			// Handle button click
-			if fetchButton.Clicked(gtx) && !currentImage.IsLoading() {
-				currentImage.SetLoading()
-				go func(wind *app.Window) {
-					img, _, err := HandleButtonClick()
-					if err != nil {
-						log.Printf("Error handling button click: %v", err)
-					} else {
-						currentImage.SetImage(img)
-					}
-					currentImage.ClearLoading()
-					wind.Invalidate()
-				}(w)
+			if clicked, retry := fetchClicked(gtx, &fetchButton, &banner, &currentImage); clicked {
+				fetch(retry)
+			}
+
+			// Handle tag choice, cats queued for the old choice are dropped
+			if tags.Update(gtx) {
+				applySettings()
+			}
+
+			// Handle says edits
+			if says.Update(gtx) {
+				settingsDue = gtx.Now.Add(settingsDelay)
+			}
+			if !settingsDue.IsZero() {
+				if gtx.Now.Before(settingsDue) {
+					gtx.Execute(op.InvalidateCmd{At: settingsDue})
+				} else {
+					applySettings()
+				}
+			}
+
+			// Handle cancel click
+			if cancelButton.Clicked(gtx) {
+				fetches.Cancel()
+				currentImage.ClearLoading()
+			}
+
+			// Handle image click
+			if imageClick.Clicked(gtx) && currentImage.IsAnimated() {
+				currentImage.TogglePause()
 			}
 
 			// Layout UI components
@@ -67,12 +178,24 @@ func Run(w *app.Window) error {
 				Spacing: layout.SpaceStart,
 			}.Layout(gtx,
 				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-					return layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-						return layoutButton(gtx, th, &fetchButton, 12)
-					})
+					return layoutFetchButtons(gtx, th, &fetchButton, &cancelButton, currentImage.IsLoading(), fetches.Running())
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return tags.Layout(gtx, th, 12)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return says.Layout(gtx, th, 12)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return banner.Layout(gtx, th, 12)
 				}),
 				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
-					return layoutImageDisplay(gtx, &currentImage, 24)
+					return imageClick.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return layoutImageDisplay(gtx, th, &currentImage, 24)
+					})
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return info.Layout(gtx, th, currentImage.Metadata(), currentImage.GetImage(), 12)
 				}),
 			)
 
@@ -82,20 +205,58 @@ func Run(w *app.Window) error {
 	}
 }
 
-// layoutButton renders the fetch button with padding and styling
-func layoutButton(gtx layout.Context, th *material.Theme, btn *widget.Clickable, insetPixels unit.Dp) layout.Dimensions {
+// fetchClicked reports whether the fetch or Retry button was clicked while
+// no cat is loading, and whether it was Retry. Disabling the button only
+// changes how it is drawn, its clicks still arrive through the loop's gtx,
+// so they are dropped here.
+func fetchClicked(gtx layout.Context, fetchButton *widget.Clickable, banner *errorBanner, img *catpic.CatPic) (clicked, retry bool) {
+	fetch := fetchButton.Clicked(gtx)
+	retry = banner.RetryClicked(gtx)
+	if img.IsLoading() {
+		return false, false
+	}
+	return fetch || retry, retry
+}
+
+// layoutFetchButtons renders the fetch button, and the Cancel button next
+// to it while a fetch runs
+func layoutFetchButtons(gtx layout.Context, th *material.Theme, fetchButton, cancelButton *widget.Clickable, loading, running bool) layout.Dimensions {
+	return layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layoutButton(gtx, th, fetchButton, loading, 12)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !running {
+					return layout.Dimensions{}
+				}
+				return layoutCancelButton(gtx, th, cancelButton, 12)
+			}),
+		)
+	})
+}
+
+// layoutButton renders the fetch button with padding and styling, disabled
+// while a cat is loading
+func layoutButton(gtx layout.Context, th *material.Theme, btn *widget.Clickable, loading bool, insetPixels unit.Dp) layout.Dimensions {
 	inset := layout.UniformInset(insetPixels)
 
-	dims := layoutButtonDims(gtx, inset, th, btn)
+	dims := layoutButtonDims(gtx, inset, th, btn, loading)
 
 	return dims
 
 }
 
-func layoutButtonDims(gtx layout.Context, inset layout.Inset, th *material.Theme, btn *widget.Clickable) layout.Dimensions {
+func layoutButtonDims(gtx layout.Context, inset layout.Inset, th *material.Theme, btn *widget.Clickable, loading bool) layout.Dimensions {
 	return inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		label := "Fetch a Cat"
+		if loading {
+			label = "Fetching..."
+			gtx = gtx.Disabled()
+		}
+
 		// Create button with styling
-		button := material.Button(th, btn, "Fetch a Cat")
+		button := material.Button(th, btn, label)
 		button.CornerRadius = unit.Dp(16)
 		button.Background = color.NRGBA{R: 189, G: 147, B: 249, A: 255}
 		button.Color = color.NRGBA{R: 248, G: 248, B: 242, A: 255}
@@ -110,17 +271,37 @@ func layoutButtonDims(gtx layout.Context, inset layout.Inset, th *material.Theme
 	})
 }
 
-// layoutImageDisplay renders the image display area with padding
-func layoutImageDisplay(gtx layout.Context, img *catpic.CatPic, insetPixels unit.Dp) layout.Dimensions {
+// layoutCancelButton renders the button abandoning the fetch in flight
+func layoutCancelButton(gtx layout.Context, th *material.Theme, btn *widget.Clickable, insetPixels unit.Dp) layout.Dimensions {
+	return layout.UniformInset(insetPixels).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		button := material.Button(th, btn, "Cancel")
+		button.CornerRadius = unit.Dp(16)
+		button.Background = color.NRGBA{R: 255, G: 85, B: 85, A: 255}
+		button.Color = color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+
+		gtx.Constraints.Min.X = gtx.Dp(120)
+		gtx.Constraints.Max.X = gtx.Dp(120)
+		gtx.Constraints.Min.Y = gtx.Dp(40)
+		gtx.Constraints.Max.Y = gtx.Dp(40)
+
+		return button.Layout(gtx)
+	})
+}
+
+// layoutImageDisplay renders the image display area with padding, and the
+// loading indicator over it
+func layoutImageDisplay(gtx layout.Context, th *material.Theme, img *catpic.CatPic, insetPixels unit.Dp) layout.Dimensions {
 	// Create the inset
 	inset := layout.UniformInset(insetPixels)
 
-	dims := layoutImageDisplayDims(gtx, img, inset)
+	dims := layoutImageDisplayDims(gtx, th, img, inset)
 
 	return dims
 
 }
 
-func layoutImageDisplayDims(gtx layout.Context, img *catpic.CatPic, inset layout.Inset) layout.Dimensions {
-	return inset.Layout(gtx, img.Draw)
+func layoutImageDisplayDims(gtx layout.Context, th *material.Theme, img *catpic.CatPic, inset layout.Inset) layout.Dimensions {
+	return inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return img.Layout(gtx, th)
+	})
 }
@@ -1,13 +1,30 @@
 package ui
 
 import (
+	"image"
 	"testing"
 	"time"
 
 	"gioui.org/app"
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
 	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/catpic"
 )
 
+// clickAt queues a left click at pos, seen by the widgets of the next frame
+func clickAt(r *input.Router, pos f32.Point) {
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+	)
+}
+
 // TestRun_Initialization tests that Run function can be initialized
 func TestRun_Initialization(t *testing.T) {
 	testutil.AssertNoPanic(t, func() {
@@ -46,9 +63,9 @@ func TestRun_ButtonClickHandling(t *testing.T) {
 	t.Run("button_click_triggers_fetch", func(t *testing.T) {
 		// The Run function should:
 		// 1. Check if button was clicked using fetchButton.Clicked(gtx)
-		// 2. Check if not already loading using !currentImage.IsLoading()
+		// 2. Begin a fetch with its own context using fetches.begin(ctx)
 		// 3. Set loading state with currentImage.SetLoading()
-		// 4. Launch goroutine with HandleButtonClick()
+		// 4. Launch goroutine with nextCat, a fresh fetch for Retry
 		// 5. Update image on success
 		// 6. Clear loading state
 		// 7. Invalidate window to trigger redraw
@@ -58,10 +75,58 @@ func TestRun_ButtonClickHandling(t *testing.T) {
 	})
 
 	t.Run("button_disabled_while_loading", func(t *testing.T) {
-		// The Run function checks !currentImage.IsLoading()
-		// to prevent multiple simultaneous fetches
+		th := material.NewTheme()
+		var r input.Router
+		var ops op.Ops
+		var fetchButton, cancelButton widget.Clickable
+		var banner errorBanner
+		var currentImage catpic.CatPic
+
+		frame := func() bool {
+			ops.Reset()
+			gtx := layout.Context{Ops: &ops, Constraints: layout.Constraints{Max: image.Pt(400, 64)}, Source: r.Source()}
+			clicked, _ := fetchClicked(gtx, &fetchButton, &banner, &currentImage)
+			layoutFetchButtons(gtx, th, &fetchButton, &cancelButton, currentImage.IsLoading(), false)
+			r.Frame(&ops)
+			return clicked
+		}
+		fetchButtonAt := f32.Pt(60, 32)
+
+		frame()
+		clickAt(&r, fetchButtonAt)
+		testutil.AssertTrue(t, frame(), "a click starts a fetch")
+
+		currentImage.SetLoading()
+		clickAt(&r, fetchButtonAt)
+		testutil.AssertFalse(t, frame(), "a click while loading is ignored")
+
+		currentImage.ClearLoading()
+		clickAt(&r, fetchButtonAt)
+		testutil.AssertTrue(t, frame(), "clicks count again once loaded")
+	})
+
+	t.Run("cancel_button_while_loading", func(t *testing.T) {
+		th := material.NewTheme()
+		var r input.Router
+		var ops op.Ops
+		var fetchButton, cancelButton widget.Clickable
+
+		frame := func(running bool) (layout.Dimensions, bool) {
+			ops.Reset()
+			gtx := layout.Context{Ops: &ops, Constraints: layout.Constraints{Max: image.Pt(400, 64)}, Source: r.Source()}
+			clicked := cancelButton.Clicked(gtx)
+			dims := layoutFetchButtons(gtx, th, &fetchButton, &cancelButton, running, running)
+			r.Frame(&ops)
+			return dims, clicked
+		}
+
+		idle, _ := frame(false)
+		running, _ := frame(true)
+		testutil.AssertTrue(t, running.Size.X > idle.Size.X, "the Cancel button shows while a fetch runs")
 
-		// This prevents race conditions and multiple network requests
+		clickAt(&r, f32.Pt(float32(idle.Size.X)+60, 32))
+		_, clicked := frame(true)
+		testutil.AssertTrue(t, clicked, "the Cancel button takes clicks")
 	})
 }
 
@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// the range of the font size slider
+const (
+	minFontSize     = 10
+	maxFontSize     = 100
+	defaultFontSize = 50
+)
+
+// saysEditor builds a cat that says something: the text, its font, size
+// and colours. Fetches use it whenever there is text.
+type saysEditor struct {
+	text widget.Editor
+
+	fonts     []api.CAASFont // by name
+	font      *api.CAASFont  // nil for the server's default
+	fontOpen  bool
+	fontPick  widget.Clickable
+	fontItems []widget.Clickable // the server default, then fonts
+	fontList  widget.List
+
+	size widget.Float
+
+	color      colorField
+	background colorField
+}
+
+// colorField is a hex colour typed by the user, with a swatch of it
+type colorField struct {
+	label  string
+	editor widget.Editor
+}
+
+func newSaysEditor() *saysEditor {
+	e := &saysEditor{
+		fonts: slices.SortedFunc(maps.Keys(api.CAASFonts), func(a, b api.CAASFont) int {
+			return cmp.Compare(api.CAASFonts[a], api.CAASFonts[b])
+		}),
+		color:      colorField{label: "Text"},
+		background: colorField{label: "Background"},
+	}
+	e.fontItems = make([]widget.Clickable, len(e.fonts)+1)
+	e.fontList.Axis = layout.Vertical
+	e.text.SingleLine = true
+	e.color.editor.SingleLine = true
+	e.background.editor.SingleLine = true
+	e.setFontSize(defaultFontSize)
+	return e
+}
+
+// Text returns the text the cat says, "" for a cat that says nothing
+func (e *saysEditor) Text() string {
+	return strings.TrimSpace(e.text.Text())
+}
+
+// FontSize returns the size picked on the slider
+func (e *saysEditor) FontSize() int {
+	return minFontSize + int(e.size.Value*(maxFontSize-minFontSize)+0.5)
+}
+
+func (e *saysEditor) setFontSize(size int) {
+	e.size.Value = float32(size-minFontSize) / (maxFontSize - minFontSize)
+}
+
+// Value returns the colour typed in, and false when there is none or it
+// isn't a valid hex colour
+func (f *colorField) Value() (string, bool) {
+	s := strings.TrimSpace(f.editor.Text())
+	_, err := api.ParseHexColor(s)
+	return s, err == nil
+}
+
+// Apply makes u a cat saying the text, styled as picked. Colours that
+// aren't valid are left to the server's defaults.
+func (e *saysEditor) Apply(u *api.CatURL) *api.CatURL {
+	text := e.Text()
+	if text == "" {
+		return u
+	}
+	u = u.WithSays(text).WithFontSize(e.FontSize())
+	if e.font != nil {
+		u = u.WithFont(*e.font)
+	}
+	if c, ok := e.color.Value(); ok {
+		u = u.WithFontColor(c)
+	}
+	if c, ok := e.background.Value(); ok {
+		u = u.WithFontBackground(c)
+	}
+	return u
+}
+
+// Update handles the editor's input, reporting whether the cat it
+// describes changed
+func (e *saysEditor) Update(gtx layout.Context) bool {
+	changed := false
+	for _, editor := range []*widget.Editor{&e.text, &e.color.editor, &e.background.editor} {
+		for {
+			ev, ok := editor.Update(gtx)
+			if !ok {
+				break
+			}
+			if _, ok := ev.(widget.ChangeEvent); ok {
+				changed = true
+			}
+		}
+	}
+	if e.fontPick.Clicked(gtx) {
+		e.fontOpen = !e.fontOpen
+	}
+	for i := range e.fontItems {
+		if !e.fontItems[i].Clicked(gtx) {
+			continue
+		}
+		e.font = nil
+		if i > 0 {
+			e.font = &e.fonts[i-1]
+		}
+		e.fontOpen = false
+		changed = true
+	}
+	if e.size.Update(gtx) {
+		changed = true
+	}
+	return changed
+}
+
+// fontName names the font picked
+func (e *saysEditor) fontName() string {
+	if e.font == nil {
+		return "Default font"
+	}
+	return api.CAASFonts[*e.font]
+}
+
+// Layout renders the text field with the font, size and colour pickers
+func (e *saysEditor) Layout(gtx layout.Context, th *material.Theme, insetPixels unit.Dp) layout.Dimensions {
+	fg := color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+	muted := color.NRGBA{R: 98, G: 114, B: 164, A: 255}
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			editor := material.Editor(th, &e.text, "Cat says...")
+			editor.Color = fg
+			editor.HintColor = muted
+			return layout.UniformInset(4).Layout(gtx, editor.Layout)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					button := material.Button(th, &e.fontPick, e.fontName()+" ▾")
+					button.CornerRadius = unit.Dp(16)
+					button.Background = muted
+					button.Color = fg
+					return button.Layout(gtx)
+				}),
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					return layout.Inset{Left: 8}.Layout(gtx, material.Slider(th, &e.size).Layout)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Dp(32)
+					label := material.Body2(th, strconv.Itoa(e.FontSize()))
+					label.Color = fg
+					return layout.Inset{Left: 4}.Layout(gtx, label.Layout)
+				}),
+			)
+		}),
+	}
+	if e.fontOpen {
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return e.layoutFonts(gtx, th)
+		}))
+	}
+	children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{}.Layout(gtx,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return e.color.Layout(gtx, th)
+			}),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return e.background.Layout(gtx, th)
+			}),
+		)
+	}))
+
+	return layout.UniformInset(insetPixels).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+// layoutFonts renders the open font dropdown
+func (e *saysEditor) layoutFonts(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	gtx.Constraints.Max.Y = min(gtx.Constraints.Max.Y, gtx.Dp(96))
+	return material.List(th, &e.fontList).Layout(gtx, len(e.fontItems), func(gtx layout.Context, i int) layout.Dimensions {
+		name, picked := "Default font", e.font == nil
+		if i > 0 {
+			name, picked = api.CAASFonts[e.fonts[i-1]], e.font != nil && *e.font == e.fonts[i-1]
+		}
+		return material.Clickable(gtx, &e.fontItems[i], func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			label := material.Body2(th, name)
+			label.Color = color.NRGBA{R: 139, G: 233, B: 253, A: 255}
+			if picked {
+				label.Color = color.NRGBA{R: 80, G: 250, B: 123, A: 255}
+			}
+			return layout.Inset{Top: 2, Bottom: 2, Left: 8, Right: 8}.Layout(gtx, label.Layout)
+		})
+	})
+}
+
+// Layout renders the field with a swatch of its colour, or a warning when
+// what was typed isn't a colour
+func (f *colorField) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	text := strings.TrimSpace(f.editor.Text())
+	c, err := api.ParseHexColor(text)
+	valid := err == nil
+
+	return layout.UniformInset(4).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				size := gtx.Dp(20)
+				if valid {
+					rect := clip.UniformRRect(image.Rect(0, 0, size, size), size/4)
+					paint.FillShape(gtx.Ops, c, rect.Op(gtx.Ops))
+				}
+				return layout.Dimensions{Size: image.Pt(size, size)}
+			}),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				editor := material.Editor(th, &f.editor, f.label+" #rrggbb")
+				editor.Color = color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+				editor.HintColor = color.NRGBA{R: 98, G: 114, B: 164, A: 255}
+				if text != "" && !valid {
+					editor.Color = color.NRGBA{R: 255, G: 85, B: 85, A: 255}
+				}
+				return layout.Inset{Left: 4}.Layout(gtx, editor.Layout)
+			}),
+		)
+	})
+}
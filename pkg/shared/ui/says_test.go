@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// TestColorField_Value tests trimming and validating typed hex colours
+func TestColorField_Value(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		valid bool
+	}{
+		{"", "", false},
+		{"#ff00ff", "#ff00ff", true},
+		{" #FFF ", "#FFF", true},
+		{"ff00ff", "ff00ff", false},
+		{"red", "red", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var f colorField
+			f.editor.SetText(tt.in)
+			got, ok := f.Value()
+			testutil.AssertEqual(t, tt.want, got, "text")
+			testutil.AssertEqual(t, tt.valid, ok, "valid")
+		})
+	}
+}
+
+// TestSaysEditor_Fonts tests that every font is offered, sorted by name
+func TestSaysEditor_Fonts(t *testing.T) {
+	e := newSaysEditor()
+	testutil.AssertEqual(t, len(api.CAASFonts), len(e.fonts), "every font is offered")
+	testutil.AssertEqual(t, len(api.CAASFonts)+1, len(e.fontItems), "plus the server default")
+	for i := 1; i < len(e.fonts); i++ {
+		testutil.AssertTrue(t, api.CAASFonts[e.fonts[i-1]] < api.CAASFonts[e.fonts[i]], "fonts are sorted by name")
+	}
+	testutil.AssertEqual(t, "Default font", e.fontName(), "no font picked")
+}
+
+// TestSaysEditor_FontSize tests mapping the slider onto the font size range
+func TestSaysEditor_FontSize(t *testing.T) {
+	e := newSaysEditor()
+	testutil.AssertEqual(t, defaultFontSize, e.FontSize(), "default size")
+
+	e.size.Value = 0
+	testutil.AssertEqual(t, minFontSize, e.FontSize(), "slider at the start")
+	e.size.Value = 1
+	testutil.AssertEqual(t, maxFontSize, e.FontSize(), "slider at the end")
+}
+
+// TestSaysEditor_Apply tests making a CatURL say the text with the chosen style
+func TestSaysEditor_Apply(t *testing.T) {
+	generate := func(u *api.CatURL) string {
+		t.Helper()
+		s, err := u.Generate()
+		testutil.AssertNoError(t, err, "generate")
+		return s
+	}
+
+	e := newSaysEditor()
+	u := api.NewCatURL()
+	testutil.AssertTrue(t, e.Apply(u) == u, "no text leaves the url alone")
+
+	e.text.SetText("  hello  ")
+	testutil.AssertEqual(t,
+		generate(api.NewCatURL().WithSays("hello").WithFontSize(defaultFontSize)),
+		generate(e.Apply(api.NewCatURL())), "text with the default size")
+
+	font := api.CAASFontImpact
+	e.font = &font
+	e.setFontSize(30)
+	e.color.editor.SetText("#ff00ff")
+	e.background.editor.SetText("#000")
+	testutil.AssertEqual(t,
+		generate(api.NewCatURL().WithSays("hello").WithFontSize(30).WithFont(font).WithFontColor("#ff00ff").WithFontBackground("#000")),
+		generate(e.Apply(api.NewCatURL())), "fully styled")
+
+	e.color.editor.SetText("magenta")
+	e.background.editor.SetText("")
+	applied := e.Apply(api.NewCatURL())
+	testutil.AssertNoError(t, applied.Err(), "invalid colours are left out")
+	testutil.AssertEqual(t,
+		generate(api.NewCatURL().WithSays("hello").WithFontSize(30).WithFont(font)),
+		generate(applied), "invalid colours use the defaults")
+
+	tagged := e.Apply(api.NewCatURL().WithTag("cute"))
+	testutil.AssertNoError(t, tagged.Err(), "says combines with a tag")
+}
+
+// TestSaysEditor_Layout tests that typing is a change and the font dropdown opens
+func TestSaysEditor_Layout(t *testing.T) {
+	th := material.NewTheme()
+	e := newSaysEditor()
+
+	layoutEditor := func() (layout.Dimensions, bool) {
+		var ops op.Ops
+		gtx := layout.Context{
+			Ops:         &ops,
+			Constraints: layout.Constraints{Max: image.Pt(400, 400)},
+		}
+		changed := e.Update(gtx)
+		return e.Layout(gtx, th, 12), changed
+	}
+
+	closed, changed := layoutEditor()
+	testutil.AssertFalse(t, changed, "no input, no change")
+	testutil.AssertTrue(t, closed.Size.Y > 0, "editor is shown")
+
+	e.fontOpen = true
+	e.color.editor.SetText("not a colour")
+	open, changed := layoutEditor()
+	testutil.AssertTrue(t, changed, "new colour text is a change")
+	testutil.AssertTrue(t, open.Size.Y > closed.Size.Y, "open dropdown lists the fonts")
+}
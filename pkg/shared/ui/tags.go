@@ -0,0 +1,329 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// maxCountLookups is how many tag counts are looked up at once
+const maxCountLookups = 4
+
+// tagPicker narrows fetches to one of the tags cataas knows. The list is
+// filtered as the user types, and the picker is disabled until the tags
+// have loaded.
+type tagPicker struct {
+	tags     *api.TagRegistry
+	counts   *tagCounter // nil shows no counts
+	selected string      // "" for any cat
+	// offline means tags not loaded from the cache by now never will be
+	offline bool
+
+	search widget.Editor
+	list   widget.List
+	items  map[string]*widget.Clickable
+	any    widget.Clickable
+
+	// filtered is filterTags for query over the tags loaded at loadedAt
+	query    string
+	loadedAt time.Time
+	filtered []string
+}
+
+// newTagPicker returns a picker over tags, showing how many cats each tag
+// has when counts isn't nil
+func newTagPicker(tags *api.TagRegistry, counts *tagCounter) *tagPicker {
+	p := &tagPicker{
+		tags:   tags,
+		counts: counts,
+		items:  make(map[string]*widget.Clickable),
+	}
+	p.search.SingleLine = true
+	p.search.Submit = true
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+// Selected returns the chosen tag, "" when fetching any cat
+func (p *tagPicker) Selected() string {
+	return p.selected
+}
+
+// Apply narrows u to the chosen tag
+func (p *tagPicker) Apply(u *api.CatURL) *api.CatURL {
+	if p.selected == "" {
+		return u
+	}
+	return u.WithTag(p.selected)
+}
+
+// tagCounter looks up how many cats tags have, in the background and a few
+// at a time, so the picker can show the counts of the tags on screen. The
+// counts are kept in the registry.
+type tagCounter struct {
+	ctx     context.Context
+	tags    *api.TagRegistry
+	client  *api.Client
+	arrived func() // called when a count arrives, e.g. to redraw
+	slots   chan struct{}
+
+	mu    sync.Mutex
+	asked map[string]bool // looked up or failed, failures aren't retried
+}
+
+func newTagCounter(ctx context.Context, tags *api.TagRegistry, client *api.Client, arrived func()) *tagCounter {
+	return &tagCounter{
+		ctx:     ctx,
+		tags:    tags,
+		client:  client,
+		arrived: arrived,
+		slots:   make(chan struct{}, maxCountLookups),
+		asked:   make(map[string]bool),
+	}
+}
+
+// Count returns the number of cats tagged tag, and false while it isn't
+// known. Missing and stale counts are looked up in the background.
+func (c *tagCounter) Count(tag string) (int, bool) {
+	count, fresh := c.tags.CachedCount(tag)
+	if !fresh {
+		c.lookup(tag)
+	}
+	return count, fresh || count > 0
+}
+
+func (c *tagCounter) lookup(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.asked[tag] {
+		return
+	}
+	c.asked[tag] = true
+
+	go func() {
+		select {
+		case c.slots <- struct{}{}:
+		case <-c.ctx.Done():
+			return
+		}
+		_, err := c.tags.Count(c.ctx, c.client, tag)
+		<-c.slots
+		if err != nil {
+			log.Printf("Error counting cats tagged %q: %v", tag, err)
+			return
+		}
+
+		// looked up again once the count goes stale
+		c.mu.Lock()
+		delete(c.asked, tag)
+		c.mu.Unlock()
+		if c.arrived != nil {
+			c.arrived()
+		}
+	}()
+}
+
+// count returns the number of cats tagged tag, false when it isn't known or
+// the picker shows no counts
+func (p *tagPicker) count(tag string) (int, bool) {
+	if p.counts == nil {
+		return 0, false
+	}
+	return p.counts.Count(tag)
+}
+
+// filterTags returns the tags containing query, ignoring case, those
+// starting with it first
+func filterTags(tags []string, query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return tags
+	}
+	var prefixed, contained []string
+	for _, tag := range tags {
+		lower := strings.ToLower(tag)
+		switch {
+		case strings.HasPrefix(lower, query):
+			prefixed = append(prefixed, tag)
+		case strings.Contains(lower, query):
+			contained = append(contained, tag)
+		}
+	}
+	return append(prefixed, contained...)
+}
+
+// Filtered returns the tags matching the search text
+func (p *tagPicker) Filtered() []string {
+	query := p.search.Text()
+	if updated := p.tags.UpdatedAt(); p.filtered == nil || query != p.query || !updated.Equal(p.loadedAt) {
+		p.query, p.loadedAt = query, updated
+		p.filtered = filterTags(p.tags.Tags(), query)
+	}
+	return p.filtered
+}
+
+func (p *tagPicker) item(tag string) *widget.Clickable {
+	c, ok := p.items[tag]
+	if !ok {
+		c = new(widget.Clickable)
+		p.items[tag] = c
+	}
+	return c
+}
+
+// Update handles the picker's input, reporting whether the chosen tag
+// changed. Submitting the search picks the first match.
+func (p *tagPicker) Update(gtx layout.Context) bool {
+	selected := p.selected
+	for {
+		ev, ok := p.search.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.SubmitEvent); ok {
+			if filtered := p.Filtered(); len(filtered) > 0 {
+				p.selected = filtered[0]
+			}
+		}
+	}
+	if p.any.Clicked(gtx) {
+		p.selected = ""
+	}
+	for tag, c := range p.items {
+		if c.Clicked(gtx) {
+			p.selected = tag
+		}
+	}
+	return p.selected != selected
+}
+
+// hint is the search field's placeholder, saying why it is disabled while
+// there are no tags to search
+func (p *tagPicker) hint(ready bool) string {
+	switch {
+	case ready:
+		return "Search tags"
+	case p.offline:
+		return "No tags available offline"
+	default:
+		return "Loading tags..."
+	}
+}
+
+// Layout renders the search field, the chosen tag and the matching tags,
+// with the number of cats of each once known
+func (p *tagPicker) Layout(gtx layout.Context, th *material.Theme, insetPixels unit.Dp) layout.Dimensions {
+	fg := color.NRGBA{R: 248, G: 248, B: 242, A: 255}
+	muted := color.NRGBA{R: 98, G: 114, B: 164, A: 255}
+	ready := p.tags.IsReady() && p.tags.Len() > 0
+
+	return layout.UniformInset(insetPixels).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		if !ready {
+			gtx = gtx.Disabled()
+		}
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+						editor := material.Editor(th, &p.search, p.hint(ready))
+						editor.Color = fg
+						editor.HintColor = muted
+						return layout.UniformInset(4).Layout(gtx, editor.Layout)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						label := "Any tag"
+						if p.selected != "" {
+							label = p.selected + " ✕"
+						}
+						button := material.Button(th, &p.any, label)
+						button.CornerRadius = unit.Dp(16)
+						button.Background = muted
+						button.Color = fg
+						return button.Layout(gtx)
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if p.selected == "" {
+					return layout.Dimensions{}
+				}
+				count, ok := p.count(p.selected)
+				if !ok {
+					return layout.Dimensions{}
+				}
+				label := material.Caption(th, countLabel(count, p.selected))
+				label.Color = muted
+				return layout.Inset{Left: 4, Bottom: 4}.Layout(gtx, label.Layout)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !ready {
+					return layout.Dimensions{}
+				}
+				gtx.Constraints.Max.Y = min(gtx.Constraints.Max.Y, gtx.Dp(96))
+				filtered := p.Filtered()
+				return material.List(th, &p.list).Layout(gtx, len(filtered), func(gtx layout.Context, i int) layout.Dimensions {
+					return p.layoutItem(gtx, th, filtered[i])
+				})
+			}),
+		)
+	})
+}
+
+// countLabel describes how many cats are tagged tag
+func countLabel(count int, tag string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 cat tagged '%s'", tag)
+	}
+	return fmt.Sprintf("%d cats tagged '%s'", count, tag)
+}
+
+// layoutItem renders one tag of the list with its count, highlighting the
+// chosen one
+func (p *tagPicker) layoutItem(gtx layout.Context, th *material.Theme, tag string) layout.Dimensions {
+	return material.Clickable(gtx, p.item(tag), func(gtx layout.Context) layout.Dimensions {
+		return layout.Stack{}.Layout(gtx,
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				if tag == p.selected {
+					paint.FillShape(gtx.Ops, color.NRGBA{R: 68, G: 71, B: 90, A: 255},
+						clip.Rect(image.Rectangle{Max: gtx.Constraints.Min}).Op())
+				}
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}),
+			layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				return layout.Inset{Top: 2, Bottom: 2, Left: 8, Right: 8}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+							label := material.Body2(th, tag)
+							label.Color = color.NRGBA{R: 139, G: 233, B: 253, A: 255}
+							return label.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							count, ok := p.count(tag)
+							if !ok {
+								return layout.Dimensions{}
+							}
+							label := material.Caption(th, strconv.Itoa(count))
+							label.Color = color.NRGBA{R: 98, G: 114, B: 164, A: 255}
+							return label.Layout(gtx)
+						}),
+					)
+				})
+			}),
+		)
+	})
+}
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/bmj2728/catfetch/internal/testutil"
+	"github.com/bmj2728/catfetch/pkg/shared/api"
+)
+
+// TestFilterTags tests case-insensitive tag search, prefix matches first
+func TestFilterTags(t *testing.T) {
+	tags := []string{"black", "Blue eyes", "cute", "sleepy black cat", "orange"}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", tags},
+		{"  ", tags},
+		{"black", []string{"black", "sleepy black cat"}},
+		{"BL", []string{"black", "Blue eyes", "sleepy black cat"}},
+		{"c", []string{"cute", "black", "sleepy black cat"}},
+		{"dog", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			testutil.AssertEqual(t, tt.want, filterTags(tags, tt.query), "filtered tags")
+		})
+	}
+}
+
+// TestTagPicker_Apply tests narrowing a CatURL to the chosen tag
+func TestTagPicker_Apply(t *testing.T) {
+	registry := api.NewTagRegistry(0, "")
+	p := newTagPicker(registry, nil)
+
+	u := api.NewCatURL()
+	testutil.AssertTrue(t, p.Apply(u) == u, "no tag leaves the url alone")
+
+	p.selected = "cute"
+	got, err := p.Apply(api.NewCatURL()).Generate()
+	testutil.AssertNoError(t, err, "generate")
+	want, _ := api.NewCatURL().WithTag("cute").Generate()
+	testutil.AssertEqual(t, want, got, "url with the chosen tag")
+}
+
+// TestTagPicker_Filtered tests listing the loaded tags matching the search
+func TestTagPicker_Filtered(t *testing.T) {
+	registry := api.NewTagRegistry(0, "")
+	p := newTagPicker(registry, nil)
+	testutil.AssertEqual(t, 0, len(p.Filtered()), "nothing before the tags load")
+
+	registry.Set(api.CAASTags{"cute", "orange", "sleepy"})
+	testutil.AssertEqual(t, 3, len(p.Filtered()), "all tags once loaded")
+
+	p.search.SetText("or")
+	testutil.AssertEqual(t, []string{"orange"}, p.Filtered(), "tags matching the search")
+}
+
+// TestTagPicker_Layout tests that the picker lists the tags once they load
+func TestTagPicker_Layout(t *testing.T) {
+	th := material.NewTheme()
+	registry := api.NewTagRegistry(0, "")
+	p := newTagPicker(registry, nil)
+
+	layoutPicker := func() layout.Dimensions {
+		var ops op.Ops
+		gtx := layout.Context{
+			Ops:         &ops,
+			Constraints: layout.Constraints{Max: image.Pt(400, 300)},
+		}
+		testutil.AssertFalse(t, p.Update(gtx), "no input, no change")
+		return p.Layout(gtx, th, 12)
+	}
+
+	loading := layoutPicker()
+	testutil.AssertTrue(t, loading.Size.Y > 0, "picker shows its loading message")
+
+	registry.Set(api.CAASTags{"cute", "orange", "sleepy"})
+	loaded := layoutPicker()
+	testutil.AssertTrue(t, loaded.Size.Y > loading.Size.Y, "picker lists the tags once loaded")
+}
+
+// TestTagPicker_Hint tests the search placeholder while tags are loading,
+// missing offline and loaded
+func TestTagPicker_Hint(t *testing.T) {
+	p := newTagPicker(api.NewTagRegistry(0, ""), nil)
+	testutil.AssertEqual(t, "Loading tags...", p.hint(false), "online, waiting for the tags")
+	testutil.AssertEqual(t, "Search tags", p.hint(true), "tags loaded")
+
+	p.offline = true
+	testutil.AssertEqual(t, "No tags available offline", p.hint(false), "offline without a tag cache")
+	testutil.AssertEqual(t, "Search tags", p.hint(true), "offline with cached tags")
+}
+
+// TestCountLabel tests the singular and plural cat count labels
+func TestCountLabel(t *testing.T) {
+	testutil.AssertEqual(t, "1 cat tagged 'orange'", countLabel(1, "orange"), "one cat")
+	testutil.AssertEqual(t, "12 cats tagged 'orange'", countLabel(12, "orange"), "many cats")
+}
+
+// TestTagCounter tests looking up each tag's cat count once and caching it
+func TestTagCounter(t *testing.T) {
+	var requests atomic.Int32
+	server := testutil.NewMockCatAPI(t, testutil.Routes{"/": testutil.Counted(&requests, func(w http.ResponseWriter, r *http.Request) {
+		count := len(r.URL.Query().Get("tags"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"count": count})
+	})})
+
+	registry := api.NewTagRegistry(0, "")
+	registry.Set(api.CAASTags{"cute", "orange"})
+	arrived := make(chan struct{}, 2)
+	counter := newTagCounter(context.Background(), registry, api.NewClient().WithBaseURL(server.URL), func() {
+		arrived <- struct{}{}
+	})
+
+	_, ok := counter.Count("orange")
+	testutil.AssertFalse(t, ok, "count unknown before the lookup answers")
+	counter.Count("orange")
+
+	select {
+	case <-arrived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("count never arrived")
+	}
+	count, ok := counter.Count("orange")
+	testutil.AssertTrue(t, ok, "count known once looked up")
+	testutil.AssertEqual(t, 6, count, "cats tagged orange")
+	testutil.AssertEqual(t, int32(1), requests.Load(), "one lookup per tag")
+
+	p := newTagPicker(registry, counter)
+	count, ok = p.count("orange")
+	testutil.AssertTrue(t, ok && count == 6, "picker shows the cached count")
+	_, ok = newTagPicker(registry, nil).count("orange")
+	testutil.AssertFalse(t, ok, "picker without a counter shows no counts")
+}